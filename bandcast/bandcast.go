@@ -0,0 +1,154 @@
+// Package bandcast sends raw band values over the network, for other
+// programs to consume the EQ, and receives them.
+//
+// Each frame is a self-contained message, so UDP receivers can join at any
+// time and stream receivers can read message after message. It is
+// little-endian throughout, like the frames binary format:
+//
+//	"LEQB" | version u8 | bands u16 | sequence u32 | time i64 |
+//	edges f32 x bands+1 | values f32 x bands
+//
+// Time is when the frame was sent, in nanoseconds since the Unix epoch.
+// The sequence number increases by one each frame, so receivers can spot
+// dropped frames.
+package bandcast
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/rabidaudio/led-eq/eq"
+)
+
+var magic = [4]byte{'L', 'E', 'Q', 'B'}
+
+// Version is the version of the format written by this package.
+const Version = 1
+
+const (
+	headerLen = 4 + 1 + 2 + 4 + 8
+	// maxUDP is the largest UDP payload
+	maxUDP = 65507
+)
+
+// MaxBands is the most bands which fit in a UDP datagram.
+const MaxBands = (maxUDP - headerLen - 4) / 8
+
+// Frame is a set of band values.
+type Frame struct {
+	Seq    uint32
+	Time   time.Time
+	Bins   eq.Bins
+	Values []float64
+}
+
+// Len is the size of the encoded frame.
+func (f Frame) Len() int {
+	return frameLen(len(f.Values))
+}
+
+func frameLen(bands int) int {
+	return headerLen + 4*(bands+1) + 4*bands
+}
+
+// AppendFrame encodes f, which must have one more bin edge than values,
+// and appends it to dst.
+func AppendFrame(dst []byte, f Frame) []byte {
+	dst = append(dst, magic[:]...)
+	dst = append(dst, Version)
+	dst = binary.LittleEndian.AppendUint16(dst, uint16(len(f.Values)))
+	dst = binary.LittleEndian.AppendUint32(dst, f.Seq)
+	dst = binary.LittleEndian.AppendUint64(dst, uint64(f.Time.UnixNano()))
+	for _, v := range f.Bins {
+		dst = binary.LittleEndian.AppendUint32(dst, math.Float32bits(float32(v)))
+	}
+	for _, v := range f.Values {
+		dst = binary.LittleEndian.AppendUint32(dst, math.Float32bits(float32(v)))
+	}
+	return dst
+}
+
+// ParseFrame decodes a frame from the start of b, returning it and its
+// length.
+func ParseFrame(b []byte) (Frame, int, error) {
+	bands, err := parseHeader(b)
+	if err != nil {
+		return Frame{}, 0, err
+	}
+	n := frameLen(bands)
+	if len(b) < n {
+		return Frame{}, 0, fmt.Errorf("bandcast: truncated frame of %d bytes, expected %d", len(b), n)
+	}
+	f := Frame{
+		Seq:    binary.LittleEndian.Uint32(b[7:]),
+		Time:   time.Unix(0, int64(binary.LittleEndian.Uint64(b[11:]))),
+		Bins:   make(eq.Bins, bands+1),
+		Values: make([]float64, bands),
+	}
+	p := b[headerLen:]
+	for i := range f.Bins {
+		f.Bins[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(p[4*i:])))
+	}
+	p = p[4*len(f.Bins):]
+	for i := range f.Values {
+		f.Values[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(p[4*i:])))
+	}
+	return f, n, nil
+}
+
+// parseHeader checks the header at the start of b, returning the band count.
+func parseHeader(b []byte) (int, error) {
+	if len(b) < headerLen {
+		return 0, fmt.Errorf("bandcast: truncated header")
+	}
+	if [4]byte(b[:4]) != magic {
+		return 0, fmt.Errorf("bandcast: not a led-eq band frame")
+	}
+	if b[4] != Version {
+		return 0, fmt.Errorf("bandcast: unsupported version %d", b[4])
+	}
+	return int(binary.LittleEndian.Uint16(b[5:])), nil
+}
+
+// ReadFrame reads the next frame from a stream, reusing buf if it's big
+// enough. It returns io.EOF if the stream ends between frames.
+func ReadFrame(r io.Reader, buf []byte) (Frame, []byte, error) {
+	buf = append(buf[:0], make([]byte, headerLen)...)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return Frame{}, buf, err
+	}
+	bands, err := parseHeader(buf)
+	if err != nil {
+		return Frame{}, buf, err
+	}
+	buf = append(buf, make([]byte, frameLen(bands)-headerLen)...)
+	if _, err := io.ReadFull(r, buf[headerLen:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return Frame{}, buf, fmt.Errorf("bandcast: truncated frame: %w", err)
+	}
+	f, _, err := ParseFrame(buf)
+	return f, buf, err
+}
+
+// splitAddress separates a "unix:" prefix from a socket path; anything else
+// is a TCP address.
+func splitAddress(addr string) (network, address string) {
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		return "unix", path
+	}
+	return "tcp", addr
+}
+
+// Listen opens a listener for [Sender.Serve]: a TCP host:port, or a Unix
+// socket given as unix:/path.
+func Listen(addr string) (net.Listener, error) {
+	return net.Listen(splitAddress(addr))
+}
@@ -0,0 +1,151 @@
+package bandcast
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rabidaudio/led-eq/eq"
+	"github.com/stretchr/testify/assert"
+)
+
+var testBins = eq.ArbitraryBins(50, 100, 200)
+
+func failIfErr(t *testing.T, err error) {
+	assert.NoError(t, err)
+	if err != nil {
+		t.FailNow()
+	}
+}
+
+func TestFrameRoundTrip(t *testing.T) {
+	f := Frame{Seq: 7, Time: time.Unix(1700000000, 123), Bins: testBins, Values: []float64{0.25, 1}}
+	b := AppendFrame([]byte("x"), f)[1:]
+	assert.Equal(t, f.Len(), len(b))
+	assert.Equal(t, []byte{'L', 'E', 'Q', 'B', Version, 2, 0, 7, 0, 0, 0}, b[:11])
+
+	got, n, err := ParseFrame(append(b, 0xff))
+	failIfErr(t, err)
+	assert.Equal(t, len(b), n)
+	assert.Equal(t, f.Seq, got.Seq)
+	assert.True(t, f.Time.Equal(got.Time))
+	assert.Equal(t, f.Bins, got.Bins)
+	assert.Equal(t, f.Values, got.Values)
+}
+
+func TestParseFrameErrors(t *testing.T) {
+	b := AppendFrame(nil, Frame{Bins: testBins, Values: []float64{0, 0}})
+	_, _, err := ParseFrame(b[:len(b)-1])
+	assert.ErrorContains(t, err, "truncated")
+	_, _, err = ParseFrame([]byte("LEQF\x01"))
+	assert.Error(t, err)
+
+	b[4] = Version + 1
+	_, _, err = ParseFrame(b)
+	assert.ErrorContains(t, err, "unsupported version")
+}
+
+func TestReadFrame(t *testing.T) {
+	var stream []byte
+	for i := range 2 {
+		stream = AppendFrame(stream, Frame{Seq: uint32(i), Bins: testBins, Values: []float64{float64(i), 0}})
+	}
+	r := bytes.NewReader(stream[:len(stream)-1])
+	f, buf, err := ReadFrame(r, nil)
+	failIfErr(t, err)
+	assert.Equal(t, []float64{0, 0}, f.Values)
+	_, buf, err = ReadFrame(r, buf)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	_, _, err = ReadFrame(r, buf)
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestSenderUDP(t *testing.T) {
+	c, err := ListenUDP("127.0.0.1:0")
+	failIfErr(t, err)
+	defer c.Close()
+	c.conn.SetReadDeadline(time.Now().Add(time.Second))
+
+	s, err := NewSender(testBins, c.LocalAddr().String())
+	failIfErr(t, err)
+	defer s.Close()
+	failIfErr(t, s.Render([]float64{0.5, 0.25}))
+	failIfErr(t, s.Render([]float64{1, 0}))
+	assert.Error(t, s.Render([]float64{1}))
+
+	for i, values := range [][]float64{{0.5, 0.25}, {1, 0}} {
+		f, err := c.Next()
+		failIfErr(t, err)
+		assert.Equal(t, uint32(i+1), f.Seq)
+		assert.Equal(t, testBins, f.Bins)
+		assert.Equal(t, values, f.Values)
+		assert.WithinDuration(t, time.Now(), f.Time, time.Second)
+	}
+}
+
+func TestSenderSendOtherBins(t *testing.T) {
+	c, err := ListenUDP("127.0.0.1:0")
+	failIfErr(t, err)
+	defer c.Close()
+	c.conn.SetReadDeadline(time.Now().Add(time.Second))
+
+	s, err := NewSender(testBins, c.LocalAddr().String())
+	failIfErr(t, err)
+	defer s.Close()
+	bins := eq.ArbitraryBins(20, 40, 80, 160)
+	failIfErr(t, s.Send(bins, []float64{0.5, 0.25, 1}))
+	assert.Error(t, s.Send(bins, []float64{0.5, 0.25}))
+
+	f, err := c.Next()
+	failIfErr(t, err)
+	assert.Equal(t, bins, f.Bins)
+	assert.Equal(t, []float64{0.5, 0.25, 1}, f.Values)
+}
+
+func TestSenderStreams(t *testing.T) {
+	for _, addr := range []string{"127.0.0.1:0", "unix:" + filepath.Join(t.TempDir(), "eq.sock")} {
+		s, err := NewSender(testBins, "")
+		failIfErr(t, err)
+		l, err := Listen(addr)
+		failIfErr(t, err)
+		s.Serve(l)
+		if addr != "127.0.0.1:0" {
+			assert.Equal(t, "unix", l.Addr().Network())
+		} else {
+			addr = l.Addr().String()
+		}
+
+		c, err := Dial(addr)
+		failIfErr(t, err)
+		for s.Clients() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		failIfErr(t, s.Render([]float64{0.5, 0.25}))
+		failIfErr(t, s.Render([]float64{1, 0}))
+		failIfErr(t, s.Close())
+
+		for _, values := range [][]float64{{0.5, 0.25}, {1, 0}} {
+			f, err := c.Next()
+			failIfErr(t, err)
+			assert.Equal(t, values, f.Values, addr)
+		}
+		_, err = c.Next()
+		assert.ErrorIs(t, err, io.EOF, addr)
+		c.Close()
+
+		_, err = net.Dial(splitAddress(addr))
+		assert.Error(t, err, "listener closed")
+	}
+}
+
+func TestSenderBands(t *testing.T) {
+	_, err := NewSender(eq.ArbitraryBins(1), "")
+	assert.Error(t, err)
+	_, err = NewSender(make(eq.Bins, MaxBands+2), "")
+	assert.Error(t, err)
+	b := AppendFrame(nil, Frame{Bins: make(eq.Bins, MaxBands+1), Values: make([]float64, MaxBands)})
+	assert.LessOrEqual(t, len(b), maxUDP)
+}
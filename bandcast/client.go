@@ -0,0 +1,70 @@
+package bandcast
+
+import (
+	"net"
+)
+
+// Client receives frames from a [Sender].
+type Client struct {
+	conn net.Conn
+	udp  bool
+	buf  []byte
+}
+
+// ListenUDP receives frames sent to addr, joining the group if it is a
+// multicast address. Datagrams which aren't frames are skipped.
+func ListenUDP(addr string) (*Client, error) {
+	ua, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	var conn *net.UDPConn
+	if ua.IP.IsMulticast() {
+		conn, err = net.ListenMulticastUDP("udp", nil, ua)
+	} else {
+		conn, err = net.ListenUDP("udp", ua)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: conn, udp: true, buf: make([]byte, maxUDP)}, nil
+}
+
+// Dial connects to a sender serving a TCP host:port, or a Unix socket given
+// as unix:/path.
+func Dial(addr string) (*Client, error) {
+	conn, err := net.Dial(splitAddress(addr))
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: conn}, nil
+}
+
+// LocalAddr is the address the client receives on.
+func (c *Client) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+// Next waits for the next frame. For a stream it returns io.EOF once the
+// sender closes the connection.
+func (c *Client) Next() (Frame, error) {
+	if !c.udp {
+		var f Frame
+		var err error
+		f, c.buf, err = ReadFrame(c.conn, c.buf)
+		return f, err
+	}
+	for {
+		n, err := c.conn.Read(c.buf[:cap(c.buf)])
+		if err != nil {
+			return Frame{}, err
+		}
+		if f, _, err := ParseFrame(c.buf[:n]); err == nil {
+			return f, nil
+		}
+	}
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,140 @@
+package bandcast
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/rabidaudio/led-eq/eq"
+)
+
+// streamTimeout is how long a stream client has to take a frame before it
+// is dropped, so a stuck client can't hold up the others
+const streamTimeout = 100 * time.Millisecond
+
+// Sender is a display which publishes each frame to a UDP address, which
+// may be a multicast group, and to every client connected to the
+// listeners it serves.
+type Sender struct {
+	bins eq.Bins
+	udp  net.Conn
+
+	mu        sync.Mutex
+	seq       uint32
+	buf       []byte
+	listeners []net.Listener
+	clients   map[net.Conn]bool
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// NewSender creates a sender for frames of bins. If udpAddr is not empty,
+// frames are sent there as datagrams.
+func NewSender(bins eq.Bins, udpAddr string) (*Sender, error) {
+	if bins.Len() < 1 || bins.Len() > MaxBands {
+		return nil, fmt.Errorf("bandcast: supports 1 to %d bands, not %d", MaxBands, bins.Len())
+	}
+	s := &Sender{bins: bins, clients: make(map[net.Conn]bool)}
+	if udpAddr != "" {
+		conn, err := net.Dial("udp", udpAddr)
+		if err != nil {
+			return nil, err
+		}
+		s.udp = conn
+	}
+	return s, nil
+}
+
+// Serve accepts stream clients on l in the background until the sender is
+// closed, which also closes l.
+func (s *Sender) Serve(l net.Listener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		l.Close()
+		return
+	}
+	s.listeners = append(s.listeners, l)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			if s.closed {
+				conn.Close()
+			} else {
+				s.clients[conn] = true
+			}
+			s.mu.Unlock()
+		}
+	}()
+}
+
+// Clients is the number of connected stream clients.
+func (s *Sender) Clients() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.clients)
+}
+
+func (s *Sender) Render(values []float64) error {
+	return s.Send(s.bins, values)
+}
+
+// Send publishes a frame of other bins than the sender was created with,
+// for when a sender is kept while the EQ changes. It is safe to call from
+// several goroutines.
+func (s *Sender) Send(bins eq.Bins, values []float64) error {
+	if len(values) != bins.Len() {
+		return fmt.Errorf("bandcast: expected %d values but got %d", bins.Len(), len(values))
+	}
+	if bins.Len() > MaxBands {
+		return fmt.Errorf("bandcast: supports 1 to %d bands, not %d", MaxBands, bins.Len())
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	s.buf = AppendFrame(s.buf[:0], Frame{Seq: s.seq, Time: time.Now(), Bins: bins, Values: values})
+
+	for conn := range s.clients {
+		conn.SetWriteDeadline(time.Now().Add(streamTimeout))
+		if _, err := conn.Write(s.buf); err != nil {
+			// the client has gone or can't keep up
+			conn.Close()
+			delete(s.clients, conn)
+		}
+	}
+
+	if s.udp != nil {
+		if _, err := s.udp.Write(s.buf); err != nil {
+			return fmt.Errorf("bandcast: %w", err)
+		}
+	}
+	return nil
+}
+
+// Close stops the listeners and disconnects every client.
+func (s *Sender) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	var errs []error
+	for _, l := range s.listeners {
+		errs = append(errs, l.Close())
+	}
+	for conn := range s.clients {
+		conn.Close()
+		delete(s.clients, conn)
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	if s.udp != nil {
+		errs = append(errs, s.udp.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,414 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/rabidaudio/led-eq/config"
+	"github.com/rabidaudio/led-eq/eq"
+)
+
+type command struct {
+	name  string
+	args  string
+	short string
+	run   func(args []string) error
+}
+
+var commands []command
+
+func init() {
+	// assigned here to avoid an initialization cycle with usage
+	commands = []command{
+		{"play", "[flags] [input.wav]", "play audio and display the EQ (default)", runPlay},
+		{"analyze", "[flags] [input.wav]", "display the EQ without an audio device", runAnalyze},
+		{"export", "[flags] -o <out> [input.wav]", "write band data to a csv, ndjson or binary file", runExport},
+		{"replay", "[flags] <frames file>", "display a file written by export", runReplay},
+		{"opc-server", "[flags]", "show frames sent by an opc display in the terminal", runOPCServer},
+		{"devices", "", "list available outputs", runDevices},
+		{"bench", "[flags]", "measure analysis speed", runBench},
+	}
+}
+
+// errUsage is returned when the command line is invalid, after printing
+// the relevant help
+var errUsage = errors.New("invalid usage")
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "usage: led-eq <command> [flags]\n\ncommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", c.name, c.short)
+	}
+	fmt.Fprintf(w, "\nRun 'led-eq <command> -h' for the flags of each command.\n")
+}
+
+// run dispatches to a command. With no command (or only flags) it plays.
+func run(args []string) error {
+	name := "play"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" {
+		usage(os.Stdout)
+		return nil
+	}
+	for _, c := range commands {
+		if c.name == name {
+			return c.run(args)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+	usage(os.Stderr)
+	return errUsage
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		for _, c := range commands {
+			if c.name == name {
+				fmt.Fprintf(fs.Output(), "usage: led-eq %s %s\n\n%s\n\nflags:\n", c.name, c.args, c.short)
+			}
+		}
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse parses flags, mapping -h and bad flags to errUsage since the flag
+// package has already printed the help
+func parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	return nil
+}
+
+// invalid reports a bad combination of flags along with the help
+func invalid(fs *flag.FlagSet, format string, a ...any) error {
+	fmt.Fprintf(fs.Output(), "%s\n\n", fmt.Sprintf(format, a...))
+	fs.Usage()
+	return errUsage
+}
+
+// edgesFlag is a comma separated list of bin edges in Hz
+type edgesFlag eq.Bins
+
+func (e *edgesFlag) String() string {
+	s := make([]string, len(*e))
+	for i, v := range *e {
+		s[i] = strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return strings.Join(s, ",")
+}
+
+func (e *edgesFlag) Set(v string) error {
+	*e = nil
+	for _, f := range strings.Split(v, ",") {
+		x, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		if err != nil {
+			return fmt.Errorf("invalid edge %q", f)
+		}
+		if len(*e) > 0 && x <= (*e)[len(*e)-1] {
+			return fmt.Errorf("edges must be increasing")
+		}
+		*e = append(*e, x)
+	}
+	if len(*e) < 2 {
+		return fmt.Errorf("need at least 2 edges")
+	}
+	return nil
+}
+
+var layouts = []string{"linear", "exponential", "octave"}
+
+// eqFlags are the analysis settings shared by the commands which run the EQ
+type eqFlags struct {
+	fs *flag.FlagSet
+
+	layout    string
+	bins      int
+	edges     edgesFlag
+	min, max  float64
+	fps       float64
+	n         int
+	normalize float64
+	db        bool
+}
+
+// defaultEdges are the bins the installation was tuned with
+var defaultEdges = edgesFlag{50, 100, 200, 400, 800, 1600, 3200, 6400, 20_000}
+
+func addEQFlags(fs *flag.FlagSet) *eqFlags {
+	f := &eqFlags{fs: fs, edges: defaultEdges}
+	fs.StringVar(&f.layout, "layout", "", "bin layout: "+strings.Join(layouts, ", ")+" (default: -edges)")
+	fs.IntVar(&f.bins, "bins", 16, "number of bins for linear and exponential layouts, or bins per octave")
+	fs.Var(&f.edges, "edges", "explicit comma separated bin edges in Hz")
+	fs.Float64Var(&f.min, "min", 20, "lowest frequency for -layout, in Hz")
+	fs.Float64Var(&f.max, "max", 20_000, "highest frequency for -layout, in Hz")
+	fs.Float64Var(&f.fps, "fps", 60, "frames per second; sets the FFT size to the next power of two")
+	fs.IntVar(&f.n, "n", 0, "explicit FFT size, instead of -fps")
+	fs.Float64Var(&f.normalize, "normalize", 2, "gain applied to the band magnitudes")
+	fs.BoolVar(&f.db, "db", false, "output bands in dB")
+	return f
+}
+
+func isSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// validate checks the flags which don't depend on the input
+func (f *eqFlags) validate() error {
+	if f.layout != "" && isSet(f.fs, "edges") {
+		return invalid(f.fs, "-layout and -edges can't be used together")
+	}
+	if f.layout == "" && (isSet(f.fs, "bins") || isSet(f.fs, "min") || isSet(f.fs, "max")) {
+		return invalid(f.fs, "-bins, -min and -max need a -layout")
+	}
+	if f.layout != "" && !slices.Contains(layouts, f.layout) {
+		return invalid(f.fs, "unknown layout %q", f.layout)
+	}
+	if f.bins < 1 {
+		return invalid(f.fs, "-bins must be at least 1")
+	}
+	if f.min < 0 || f.max <= f.min {
+		return invalid(f.fs, "-min must be less than -max")
+	}
+	if (f.layout == "exponential" || f.layout == "octave") && f.min == 0 {
+		return invalid(f.fs, "-min must be above 0 for %s bins", f.layout)
+	}
+	if isSet(f.fs, "fps") && isSet(f.fs, "n") {
+		return invalid(f.fs, "-fps and -n can't be used together")
+	}
+	if f.fps <= 0 {
+		return invalid(f.fs, "-fps must be positive")
+	}
+	if f.n < 0 {
+		return invalid(f.fs, "-n must be positive")
+	}
+	if f.normalize <= 0 {
+		return invalid(f.fs, "-normalize must be positive")
+	}
+	return nil
+}
+
+// config converts the flags to their config file equivalent
+func (f *eqFlags) config() config.EQ {
+	c := config.EQ{
+		N:         f.n,
+		Normalize: f.normalize,
+		OutputDB:  f.db,
+		Bins:      config.Bins{Preset: f.layout},
+	}
+	if c.N == 0 {
+		c.FPS = f.fps
+	}
+	if f.layout == "" {
+		c.Bins.Edges = f.edges
+	} else {
+		c.Bins.Count = f.bins
+		c.Bins.Min = f.min
+		c.Bins.Max = f.max
+	}
+	return c
+}
+
+// eq builds the EQ for a source at sampleRate
+func (f *eqFlags) eq(sampleRate int) (eq.EQ, error) {
+	c := f.config()
+	return c.Build(sampleRate)
+}
+
+// pipelineFlags are the flags of the commands which run the whole pipeline:
+// either a config file, or the individual settings
+type pipelineFlags struct {
+	fs *flag.FlagSet
+	ef *eqFlags
+
+	config  string
+	rate    int
+	display string
+	delay   config.Delay
+	metrics string
+}
+
+var displayKinds = []string{"terminal", "none"}
+
+func addPipelineFlags(fs *flag.FlagSet) *pipelineFlags {
+	f := &pipelineFlags{fs: fs, ef: addEQFlags(fs)}
+	fs.StringVar(&f.config, "config", "", "load settings from a yaml config file instead of flags; changes are applied while running")
+	fs.IntVar(&f.rate, "rate", analysisRate, "resample the input to this rate before analysis, 0 to disable")
+	fs.StringVar(&f.display, "display", "terminal", "output: "+strings.Join(displayKinds, ", "))
+	f.delay = config.Delay{Auto: true}
+	fs.Var(&f.delay, "delay", "hold frames back this long to line them up with the audio, or auto to estimate the output latency")
+	fs.StringVar(&f.metrics, "metrics", "", "serve Prometheus metrics at http://`address`/metrics, such as :9100")
+	return f
+}
+
+// load validates the flags and builds the config they describe. The
+// optional positional argument is the input, which overrides the config.
+func (f *pipelineFlags) load() (config.Config, error) {
+	if f.fs.NArg() > 1 {
+		return config.Config{}, invalid(f.fs, "only one input can be given")
+	}
+	var cfg config.Config
+	if f.config != "" {
+		var conflicts []string
+		f.fs.Visit(func(fl *flag.Flag) {
+			if fl.Name != "config" && fl.Name != "fast" && fl.Name != "metrics" {
+				conflicts = append(conflicts, "-"+fl.Name)
+			}
+		})
+		if len(conflicts) > 0 {
+			return cfg, invalid(f.fs, "-config can't be used with %s", strings.Join(conflicts, ", "))
+		}
+		var err error
+		cfg, err = config.Load(f.config)
+		if err != nil {
+			return cfg, err
+		}
+	} else {
+		if err := f.ef.validate(); err != nil {
+			return cfg, err
+		}
+		if f.rate < 0 {
+			return cfg, invalid(f.fs, "-rate must be positive")
+		}
+		if !slices.Contains(displayKinds, f.display) {
+			return cfg, invalid(f.fs, "unknown display %q", f.display)
+		}
+		cfg.EQ = f.ef.config()
+		cfg.EQ.SampleRate = f.rate
+		cfg.OnDisplayError = "drop"
+		cfg.Delay = f.delay
+		if f.display != "none" {
+			cfg.Displays = []config.Display{{Type: f.display}}
+		}
+	}
+	if f.fs.NArg() == 1 {
+		cfg.Input.Path = f.fs.Arg(0)
+	}
+	if f.metrics != "" {
+		cfg.Metrics = f.metrics
+	}
+	return cfg, nil
+}
+
+func runPlay(args []string) error {
+	fs := newFlagSet("play")
+	pf := addPipelineFlags(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	cfg, err := pf.load()
+	if err != nil {
+		return err
+	}
+	return runPipeline(cfg, pf.config, func(s beep.Streamer, e *eq.EQ) error {
+		return playSpeaker(s, e.SampleRate, e.N)
+	}, func(e *eq.EQ) time.Duration {
+		return speakerLatency(e.SampleRate, e.N)
+	})
+}
+
+func runAnalyze(args []string) error {
+	fs := newFlagSet("analyze")
+	pf := addPipelineFlags(fs)
+	fast := fs.Bool("fast", false, "run as fast as possible instead of in real time")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	cfg, err := pf.load()
+	if err != nil {
+		return err
+	}
+	pacing := RealTime
+	if *fast {
+		pacing = AsFastAsPossible
+	}
+	return runPipeline(cfg, pf.config, func(s beep.Streamer, e *eq.EQ) error {
+		return Pace(s, e.SampleRate, e.N, pacing)
+	}, nil)
+}
+
+func runDevices(args []string) error {
+	fs := newFlagSet("devices")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	fmt.Println("audio output:")
+	fmt.Printf("  %s\n", audioOutputName)
+	fmt.Println("displays:")
+	for _, d := range displayKinds {
+		fmt.Printf("  %s\n", d)
+	}
+	for _, d := range config.DisplayTypes {
+		if !slices.Contains(displayKinds, d) {
+			fmt.Printf("  %s (with -config)\n", d)
+		}
+	}
+	fmt.Println("serial ports:")
+	for _, pattern := range serialPorts {
+		ports, _ := filepath.Glob(pattern)
+		for _, p := range ports {
+			fmt.Printf("  %s\n", p)
+		}
+	}
+	return nil
+}
+
+// where USB serial adapters and Arduinos show up
+var serialPorts = []string{"/dev/ttyUSB*", "/dev/ttyACM*", "/dev/cu.usb*"}
+
+func runBench(args []string) error {
+	fs := newFlagSet("bench")
+	ef := addEQFlags(fs)
+	rate := fs.Int("rate", analysisRate, "sample rate to analyze at")
+	frames := fs.Int("frames", 2000, "number of frames to compute")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := ef.validate(); err != nil {
+		return err
+	}
+	if *rate <= 0 || *frames <= 0 {
+		return invalid(fs, "-rate and -frames must be positive")
+	}
+	e, err := ef.eq(*rate)
+	if err != nil {
+		return err
+	}
+
+	samples := make([]float64, e.N)
+	for i := range samples {
+		samples[i] = float64(i%100)/50 - 1 // saw wave
+	}
+	out := make([]float64, e.OutBins.Len())
+	start := time.Now()
+	for range *frames {
+		e.Compute(samples, out)
+	}
+	elapsed := time.Since(start)
+
+	per := elapsed / time.Duration(*frames)
+	budget := time.Duration(e.N) * time.Second / time.Duration(e.SampleRate)
+	fmt.Printf("N=%d bins=%d rate=%d\n", e.N, e.OutBins.Len(), e.SampleRate)
+	fmt.Printf("%d frames in %v, %v per frame\n", *frames, elapsed, per)
+	fmt.Printf("%.1fx real time (%v of audio per frame)\n", float64(budget)/float64(per), budget)
+	return nil
+}
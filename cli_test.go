@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"testing"
+
+	"github.com/rabidaudio/led-eq/eq"
+	"github.com/stretchr/testify/assert"
+)
+
+func parseEQFlags(t *testing.T, args ...string) (*eqFlags, error) {
+	fs := newFlagSet("test")
+	fs.SetOutput(io.Discard)
+	f := addEQFlags(fs)
+	if err := parse(fs, args); err != nil {
+		return f, err
+	}
+	return f, f.validate()
+}
+
+func TestEQFlagsDefault(t *testing.T) {
+	f, err := parseEQFlags(t)
+	assert.NoError(t, err)
+
+	e, err := f.eq(48_000)
+	assert.NoError(t, err)
+	assert.Equal(t, 1024, e.N)
+	assert.Equal(t, eq.Bins(defaultEdges), e.OutBins)
+	assert.Equal(t, 2.0, e.Normalize)
+	assert.False(t, e.OutputDB)
+}
+
+func TestEQFlagsLayouts(t *testing.T) {
+	f, err := parseEQFlags(t, "-layout", "exponential", "-bins", "3", "-n", "4096", "-db")
+	assert.NoError(t, err)
+	e, err := f.eq(48_000)
+	assert.NoError(t, err)
+	assert.Equal(t, eq.ExponentialBins(20, 20_000, 3), e.OutBins)
+	assert.Equal(t, 4096, e.N)
+	assert.True(t, e.OutputDB)
+
+	f, err = parseEQFlags(t, "-layout", "linear", "-min", "0", "-max", "1000", "-bins", "4")
+	assert.NoError(t, err)
+	e, err = f.eq(48_000)
+	assert.NoError(t, err)
+	assert.Equal(t, eq.LinearBins(0, 1000, 4), e.OutBins)
+
+	f, err = parseEQFlags(t, "-edges", "10, 20,40")
+	assert.NoError(t, err)
+	e, err = f.eq(48_000)
+	assert.NoError(t, err)
+	assert.Equal(t, eq.ArbitraryBins(10, 20, 40), e.OutBins)
+}
+
+func TestEQFlagsInvalid(t *testing.T) {
+	for _, args := range [][]string{
+		{"-layout", "linear", "-edges", "1,2"},
+		{"-bins", "4"},
+		{"-layout", "cubic"},
+		{"-layout", "octave", "-min", "0"},
+		{"-layout", "linear", "-min", "100", "-max", "50"},
+		{"-fps", "30", "-n", "1024"},
+		{"-fps", "0"},
+		{"-normalize", "-1"},
+		{"-edges", "100,50"},
+		{"-edges", "100"},
+	} {
+		_, err := parseEQFlags(t, args...)
+		assert.ErrorIs(t, err, errUsage, "%v", args)
+	}
+}
+
+func TestEQFlagsAboveNyquist(t *testing.T) {
+	f, err := parseEQFlags(t)
+	assert.NoError(t, err)
+	_, err = f.eq(22_050)
+	assert.Error(t, err)
+}
+
+func TestUnknownCommand(t *testing.T) {
+	assert.ErrorIs(t, run([]string{"dance"}), errUsage)
+}
+
+func TestIsSet(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Int("a", 1, "")
+	fs.Int("b", 1, "")
+	assert.NoError(t, fs.Parse([]string{"-a", "1"}))
+	assert.True(t, isSet(fs, "a"))
+	assert.False(t, isSet(fs, "b"))
+}
@@ -0,0 +1,559 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/rabidaudio/led-eq/eq"
+	"gopkg.in/yaml.v3"
+)
+
+// Config describes the whole pipeline: where audio comes from, how it is
+// analyzed, how the bands are post-processed and where they are displayed.
+//
+//	input:
+//	  path: song.wav
+//	eq:
+//	  sample_rate: 48000
+//	  fps: 60
+//	  bins:
+//	    preset: exponential
+//	    count: 16
+//	  normalize: 2
+//	post:
+//	  - type: smooth
+//	    release: 200ms
+//	displays:
+//	  - type: terminal
+//	on_display_error: drop
+//	delay: auto
+//	metrics: :9100
+type Config struct {
+	Input    Input     `yaml:"input"`
+	EQ       EQ        `yaml:"eq"`
+	Post     []Stage   `yaml:"post"`
+	Displays []Display `yaml:"displays"`
+	// OnDisplayError is what to do when one of several displays fails:
+	// drop the frame, disable the display, or abort
+	OnDisplayError string `yaml:"on_display_error"`
+	// Delay holds frames back so they're shown when their audio is heard
+	Delay Delay `yaml:"delay"`
+	// Metrics is where to serve Prometheus metrics over HTTP, such as
+	// :9100; empty disables them
+	Metrics string `yaml:"metrics"`
+}
+
+type Input struct {
+	// Path of a wav file, or "-" or empty for stdin
+	Path string `yaml:"path"`
+}
+
+type EQ struct {
+	// SampleRate resamples the input to this rate before analysis. Zero
+	// analyzes at the rate of the source.
+	SampleRate int `yaml:"sample_rate"`
+	// N sets the FFT size directly. Otherwise it is picked from FPS.
+	N         int     `yaml:"n"`
+	FPS       float64 `yaml:"fps"`
+	Bins      Bins    `yaml:"bins"`
+	Normalize float64 `yaml:"normalize"`
+	OutputDB  bool    `yaml:"db"`
+}
+
+// Bins is either a preset layout or explicit edges.
+type Bins struct {
+	Preset string `yaml:"preset"`
+	// Count is the number of bins, or bins per octave for the octave preset
+	Count int       `yaml:"count"`
+	Min   float64   `yaml:"min"`
+	Max   float64   `yaml:"max"`
+	Edges []float64 `yaml:"edges"`
+}
+
+var Presets = []string{"linear", "exponential", "octave"}
+
+// Stage is a post-processing step; which fields apply depends on Type.
+type Stage struct {
+	Type string `yaml:"type"`
+	// gain
+	Gain float64 `yaml:"gain"`
+	// clip
+	Min float64 `yaml:"min"`
+	Max float64 `yaml:"max"`
+	// smooth
+	Attack  time.Duration `yaml:"attack"`
+	Release time.Duration `yaml:"release"`
+}
+
+var StageTypes = []string{"gain", "clip", "smooth"}
+
+// Display is an output; which fields apply depends on Type.
+type Display struct {
+	Type string `yaml:"type"`
+	// adalight and tpm2: the serial device and its speed (0 leaves it as
+	// is); midi: the port to write to
+	Device string `yaml:"device"`
+	Baud   int    `yaml:"baud"`
+	// network outputs: the receiver as host or host:port; web: where to
+	// serve, :8080 by default
+	Address string `yaml:"address"`
+	// e131 and artnet: where the strip starts, and how many channels of
+	// each universe to use (0 fits whole pixels). For opc, channel is the
+	// OPC channel, with 0 sending to all of them.
+	Universe     int `yaml:"universe"`
+	Channel      int `yaml:"channel"`
+	UniverseSize int `yaml:"universe_size"`
+	// e131
+	Priority   int    `yaml:"priority"`
+	SourceName string `yaml:"source_name"`
+	// wled: the realtime protocol, and how long WLED waits after the last
+	// frame before going back to its own effects
+	Protocol string        `yaml:"protocol"`
+	Timeout  time.Duration `yaml:"timeout"`
+	// bands: TCP addresses, or Unix sockets as unix:/path, for clients to
+	// connect to; address is where to send frames over UDP, which may be a
+	// multicast group
+	Listen []string `yaml:"listen"`
+	// osc: the addresses to send to
+	OSC *OSC `yaml:"osc"`
+	// midi: how bands map to control changes
+	MIDI *MIDI `yaml:"midi"`
+	// mqtt: the topics to publish to, with address as the broker
+	MQTT *MQTT `yaml:"mqtt"`
+	// LED outputs
+	LED *LED `yaml:"led"`
+}
+
+var DisplayTypes = []string{"terminal", "adalight", "tpm2", "e131", "artnet", "wled", "ddp", "opc", "bands", "osc", "midi", "web", "mqtt"}
+
+var WLEDProtocols = []string{"warls", "drgb", "drgbw", "dnrgb"}
+
+// IsLED reports whether the display drives LEDs, so needs an led section.
+func (d *Display) IsLED() bool {
+	switch d.Type {
+	case "adalight", "tpm2", "e131", "artnet", "wled", "ddp", "opc":
+		return true
+	}
+	return false
+}
+
+var DisplayErrorPolicies = []string{"drop", "disable", "abort"}
+
+// Delay is either a fixed duration or "auto", which estimates the latency
+// of the audio output.
+type Delay struct {
+	Auto     bool
+	Duration time.Duration
+}
+
+func ParseDelay(s string) (Delay, error) {
+	if s == "auto" {
+		return Delay{Auto: true}, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return Delay{}, fmt.Errorf("expected a duration or auto, got %q", s)
+	}
+	if d < 0 {
+		return Delay{}, fmt.Errorf("must not be negative")
+	}
+	return Delay{Duration: d}, nil
+}
+
+// Resolve is the delay to use for an output with the estimated latency.
+func (d Delay) Resolve(estimate time.Duration) time.Duration {
+	if d.Auto {
+		return estimate
+	}
+	return d.Duration
+}
+
+func (d Delay) String() string {
+	if d.Auto {
+		return "auto"
+	}
+	return d.Duration.String()
+}
+
+// Set makes Delay a flag.Value.
+func (d *Delay) Set(s string) error {
+	v, err := ParseDelay(s)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+func (d *Delay) UnmarshalYAML(n *yaml.Node) error {
+	var s string
+	if err := n.Decode(&s); err != nil {
+		return err
+	}
+	if err := d.Set(s); err != nil {
+		return fmt.Errorf("line %d: delay: %w", n.Line, err)
+	}
+	return nil
+}
+
+// Error is a validation error for a specific key, such as eq.bins.count or
+// displays[1].type
+type Error struct {
+	Key string
+	Err error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("config: %s: %v", e.Key, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func keyErr(key string, format string, a ...any) error {
+	return &Error{Key: key, Err: fmt.Errorf(format, a...)}
+}
+
+// Default matches the command line defaults.
+func Default() Config {
+	return Config{
+		EQ: EQ{
+			SampleRate: 48_000,
+			FPS:        60,
+			Bins:       Bins{Edges: []float64{50, 100, 200, 400, 800, 1600, 3200, 6400, 20_000}},
+			Normalize:  2,
+		},
+		Displays:       []Display{{Type: "terminal"}},
+		OnDisplayError: "drop",
+		Delay:          Delay{Auto: true},
+	}
+}
+
+// Load reads and validates a config file.
+func Load(path string) (Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Config{}, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Read parses and validates a config. Keys which are left out keep their
+// [Default] values, except that bins and displays are replaced entirely and
+// setting n replaces the default fps. Unknown keys are an error, so typos
+// don't go unnoticed.
+func Read(r io.Reader) (Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Config{}, err
+	}
+	c := Default()
+	// setting bins or n replaces the defaults rather than merging with them
+	var raw struct {
+		EQ map[string]any `yaml:"eq"`
+	}
+	if err := yaml.Unmarshal(data, &raw); err == nil {
+		if _, ok := raw.EQ["bins"]; ok {
+			c.EQ.Bins = Bins{}
+		}
+		if _, ok := raw.EQ["n"]; ok {
+			c.EQ.FPS = 0
+		}
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+		return Config{}, fmt.Errorf("config: %w", err)
+	}
+	for i := range c.Displays {
+		d := &c.Displays[i]
+		if d.LED != nil {
+			d.LED.setDefaults()
+		}
+		if d.Type == "osc" {
+			if d.OSC == nil {
+				d.OSC = &OSC{}
+			}
+			d.OSC.setDefaults()
+		}
+		if d.Type == "mqtt" {
+			if d.MQTT == nil {
+				d.MQTT = &MQTT{}
+			}
+			d.MQTT.setDefaults()
+		}
+	}
+	return c, c.Validate()
+}
+
+func (c *Config) Validate() error {
+	if err := c.EQ.validate("eq"); err != nil {
+		return err
+	}
+	for i, s := range c.Post {
+		if err := s.validate(fmt.Sprintf("post[%d]", i)); err != nil {
+			return err
+		}
+	}
+	if len(c.Displays) == 0 {
+		return keyErr("displays", "at least one display is required")
+	}
+	if !slices.Contains(DisplayErrorPolicies, c.OnDisplayError) {
+		return keyErr("on_display_error", "unknown policy %q (expected one of %v)", c.OnDisplayError, DisplayErrorPolicies)
+	}
+	terminals := 0
+	for i, d := range c.Displays {
+		key := fmt.Sprintf("displays[%d]", i)
+		if err := d.validate(key); err != nil {
+			return err
+		}
+		if d.Type == "terminal" {
+			terminals++
+			if terminals > 1 {
+				return keyErr(key+".type", "only one terminal display is allowed")
+			}
+		}
+	}
+	return nil
+}
+
+func (e *EQ) validate(key string) error {
+	if e.SampleRate < 0 {
+		return keyErr(key+".sample_rate", "must be positive")
+	}
+	if e.N < 0 {
+		return keyErr(key+".n", "must be positive")
+	}
+	if e.N != 0 && e.FPS != 0 {
+		return keyErr(key+".n", "can't be used with fps")
+	}
+	if e.N == 0 && e.FPS <= 0 {
+		return keyErr(key+".fps", "must be positive")
+	}
+	if e.Normalize <= 0 {
+		return keyErr(key+".normalize", "must be positive")
+	}
+	return e.Bins.validate(key + ".bins")
+}
+
+func (b *Bins) validate(key string) error {
+	if b.Preset == "" {
+		if b.Count != 0 || b.Min != 0 || b.Max != 0 {
+			return keyErr(key+".preset", "is required with count, min and max")
+		}
+		if len(b.Edges) < 2 {
+			return keyErr(key+".edges", "need at least 2 edges (or a preset)")
+		}
+		for i := 1; i < len(b.Edges); i++ {
+			if b.Edges[i] <= b.Edges[i-1] {
+				return keyErr(fmt.Sprintf("%s.edges[%d]", key, i), "edges must be increasing")
+			}
+		}
+		return nil
+	}
+	if len(b.Edges) > 0 {
+		return keyErr(key+".edges", "can't be used with a preset")
+	}
+	switch b.Preset {
+	case "linear":
+	case "exponential", "octave":
+		if b.Min <= 0 {
+			return keyErr(key+".min", "must be above 0 for %s bins", b.Preset)
+		}
+	default:
+		return keyErr(key+".preset", "unknown preset %q (expected one of %v)", b.Preset, Presets)
+	}
+	if b.Count < 1 {
+		return keyErr(key+".count", "must be at least 1")
+	}
+	if b.Min < 0 || b.Max <= b.Min {
+		return keyErr(key+".max", "must be above min")
+	}
+	return nil
+}
+
+func (s *Stage) validate(key string) error {
+	switch s.Type {
+	case "gain":
+		if s.Gain <= 0 {
+			return keyErr(key+".gain", "must be positive")
+		}
+	case "clip":
+		if s.Max <= s.Min {
+			return keyErr(key+".max", "must be above min")
+		}
+	case "smooth":
+		if s.Attack < 0 {
+			return keyErr(key+".attack", "must be positive")
+		}
+		if s.Release < 0 {
+			return keyErr(key+".release", "must be positive")
+		}
+	case "":
+		return keyErr(key+".type", "is required")
+	default:
+		return keyErr(key+".type", "unknown stage %q (expected one of %v)", s.Type, StageTypes)
+	}
+	return nil
+}
+
+func (d *Display) validate(key string) error {
+	switch d.Type {
+	case "terminal":
+	case "adalight", "tpm2":
+		if d.Device == "" {
+			return keyErr(key+".device", "is required")
+		}
+		if d.Baud < 0 {
+			return keyErr(key+".baud", "must be positive")
+		}
+	case "e131", "artnet":
+		if d.Universe < 0 {
+			return keyErr(key+".universe", "must be positive")
+		}
+		if d.Type == "e131" && d.Universe == 0 {
+			return keyErr(key+".universe", "e131 universes start at 1")
+		}
+		if d.Channel < 0 || d.Channel > 512 {
+			return keyErr(key+".channel", "must be from 1 to 512")
+		}
+		if d.UniverseSize < 0 || d.UniverseSize > 512 {
+			return keyErr(key+".universe_size", "must be from 1 to 512")
+		}
+		if d.Priority < 0 || d.Priority > 200 {
+			return keyErr(key+".priority", "must be from 0 to 200")
+		}
+	case "wled", "ddp":
+		if d.Address == "" {
+			return keyErr(key+".address", "is required")
+		}
+		if d.Type == "wled" && d.Protocol != "" && !slices.Contains(WLEDProtocols, d.Protocol) {
+			return keyErr(key+".protocol", "unknown protocol %q (expected one of %v)", d.Protocol, WLEDProtocols)
+		}
+		if d.Timeout < 0 {
+			return keyErr(key+".timeout", "must be positive")
+		}
+	case "opc":
+		if d.Channel < 0 || d.Channel > 255 {
+			return keyErr(key+".channel", "must be from 0 to 255")
+		}
+	case "bands":
+		if d.Address == "" && len(d.Listen) == 0 {
+			return keyErr(key+".address", "an address or listen is required")
+		}
+		for i, l := range d.Listen {
+			if l == "" {
+				return keyErr(fmt.Sprintf("%s.listen[%d]", key, i), "is empty")
+			}
+		}
+	case "osc":
+		if d.Address == "" {
+			return keyErr(key+".address", "is required")
+		}
+		if d.OSC != nil {
+			if err := d.OSC.validate(key + ".osc"); err != nil {
+				return err
+			}
+		}
+	case "web":
+	case "mqtt":
+		if d.Address == "" {
+			return keyErr(key+".address", "the broker is required")
+		}
+		if d.MQTT != nil {
+			if err := d.MQTT.validate(key + ".mqtt"); err != nil {
+				return err
+			}
+		}
+	case "midi":
+		var file string
+		if d.MIDI != nil {
+			if err := d.MIDI.validate(key + ".midi"); err != nil {
+				return err
+			}
+			file = d.MIDI.File
+		}
+		if d.Device == "" && file == "" {
+			return keyErr(key+".device", "a device or midi.file is required")
+		}
+		if d.Device != "" && file != "" {
+			return keyErr(key+".midi.file", "can't be used with a device")
+		}
+	case "":
+		return keyErr(key+".type", "is required")
+	default:
+		return keyErr(key+".type", "unknown display %q (expected one of %v)", d.Type, DisplayTypes)
+	}
+	if d.OSC != nil && d.Type != "osc" {
+		return keyErr(key+".osc", "only applies to osc outputs")
+	}
+	if d.MIDI != nil && d.Type != "midi" {
+		return keyErr(key+".midi", "only applies to midi outputs")
+	}
+	if d.MQTT != nil && d.Type != "mqtt" {
+		return keyErr(key+".mqtt", "only applies to mqtt outputs")
+	}
+	if d.IsLED() {
+		if d.LED == nil {
+			return keyErr(key+".led", "is required")
+		}
+		return d.LED.validate(key + ".led")
+	}
+	if d.LED != nil {
+		return keyErr(key+".led", "only applies to LED outputs")
+	}
+	return nil
+}
+
+// Build creates the EQ for a source at sampleRate (which should already be
+// resampled to SampleRate, if set).
+func (e *EQ) Build(sampleRate int) (eq.EQ, error) {
+	out := eq.EQ{
+		SampleRate: sampleRate,
+		N:          e.N,
+		Normalize:  e.Normalize,
+		OutputDB:   e.OutputDB,
+	}
+	if out.N == 0 {
+		out.N = eq.NForTimeStep(sampleRate, time.Duration(float64(time.Second)/e.FPS), eq.AtLeast)
+	}
+	switch e.Bins.Preset {
+	case "linear":
+		out.OutBins = eq.LinearBins(e.Bins.Min, e.Bins.Max, e.Bins.Count)
+	case "exponential":
+		out.OutBins = eq.ExponentialBins(e.Bins.Min, e.Bins.Max, e.Bins.Count)
+	case "octave":
+		out.OutBins = eq.OctaveBins(e.Bins.Min, e.Bins.Max, e.Bins.Count)
+	default:
+		out.OutBins = eq.ArbitraryBins(e.Bins.Edges...)
+	}
+	if nyquist := float64(sampleRate) / 2; out.OutBins[len(out.OutBins)-1] > nyquist {
+		return out, keyErr("eq.bins", "highest edge %v Hz is above the %v Hz limit of a %d Hz source",
+			out.OutBins[len(out.OutBins)-1], nyquist, sampleRate)
+	}
+	return out, nil
+}
+
+// BuildPost creates the post-processing chain for frames spaced period apart.
+func (c *Config) BuildPost(period time.Duration) eq.Chain {
+	chain := make(eq.Chain, len(c.Post))
+	for i, s := range c.Post {
+		switch s.Type {
+		case "gain":
+			chain[i] = eq.Gain(s.Gain)
+		case "clip":
+			chain[i] = eq.Clip{Min: s.Min, Max: s.Max}
+		case "smooth":
+			chain[i] = eq.NewSmooth(s.Attack, s.Release, period)
+		}
+	}
+	return chain
+}
@@ -0,0 +1,320 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rabidaudio/led-eq/eq"
+	"github.com/rabidaudio/led-eq/led"
+	"github.com/rabidaudio/led-eq/midi"
+	"github.com/rabidaudio/led-eq/mqtt"
+	"github.com/rabidaudio/led-eq/osc"
+	"github.com/stretchr/testify/assert"
+)
+
+func failIfErr(t *testing.T, err error) {
+	assert.NoError(t, err)
+	if err != nil {
+		t.FailNow()
+	}
+}
+
+func TestLoadExample(t *testing.T) {
+	c, err := Load("testdata/example.yaml")
+	failIfErr(t, err)
+
+	assert.Equal(t, "song.wav", c.Input.Path)
+	assert.Equal(t, Bins{Preset: "exponential", Count: 12, Min: 40, Max: 16_000}, c.EQ.Bins)
+	assert.Len(t, c.Post, 3)
+	assert.Equal(t, 250*time.Millisecond, c.Post[1].Release)
+	assert.Equal(t, []Display{{Type: "terminal"}}, c.Displays)
+	assert.Equal(t, Delay{Duration: 40 * time.Millisecond}, c.Delay)
+
+	e, err := c.EQ.Build(48_000)
+	failIfErr(t, err)
+	assert.Equal(t, 2048, e.N)
+	assert.Equal(t, 3.0, e.Normalize)
+	assert.Equal(t, eq.ExponentialBins(40, 16_000, 12), e.OutBins)
+
+	chain := c.BuildPost(10 * time.Millisecond)
+	assert.Equal(t, eq.Gain(1.5), chain[0])
+	assert.IsType(t, &eq.Smooth{}, chain[1])
+	assert.Equal(t, eq.Clip{Min: 0, Max: 1}, chain[2])
+}
+
+func TestEmptyIsDefault(t *testing.T) {
+	c, err := Read(strings.NewReader(""))
+	failIfErr(t, err)
+	assert.Equal(t, Default(), c)
+}
+
+func TestOverrides(t *testing.T) {
+	c, err := Read(strings.NewReader(`
+eq:
+  n: 4096
+  bins:
+    edges: [100, 1000, 10000]
+`))
+	failIfErr(t, err)
+
+	assert.Equal(t, 4096, c.EQ.N)
+	assert.Equal(t, 0.0, c.EQ.FPS, "n replaces the default fps")
+	assert.Equal(t, 2.0, c.EQ.Normalize, "unset keys keep their default")
+	assert.Equal(t, Bins{Edges: []float64{100, 1000, 10_000}}, c.EQ.Bins)
+
+	e, err := c.EQ.Build(44_100)
+	failIfErr(t, err)
+	assert.Equal(t, 4096, e.N)
+	assert.Equal(t, 44_100, e.SampleRate)
+}
+
+func TestDelay(t *testing.T) {
+	c, err := Read(strings.NewReader("delay: auto"))
+	failIfErr(t, err)
+	assert.Equal(t, 30*time.Millisecond, c.Delay.Resolve(30*time.Millisecond))
+
+	c, err = Read(strings.NewReader("delay: 0"))
+	failIfErr(t, err)
+	assert.Equal(t, time.Duration(0), c.Delay.Resolve(30*time.Millisecond))
+
+	_, err = Read(strings.NewReader("delay: soon"))
+	assert.ErrorContains(t, err, "delay")
+	_, err = Read(strings.NewReader("delay: -5ms"))
+	assert.ErrorContains(t, err, "negative")
+}
+
+func TestValidationNamesKey(t *testing.T) {
+	for _, tc := range []struct {
+		yaml, key string
+	}{
+		{"eq: {normalize: 0}", "eq.normalize"},
+		{"eq: {n: -1}", "eq.n"},
+		{"eq: {n: 1024, fps: 30}", "eq.n"},
+		{"eq: {fps: 0}", "eq.fps"},
+		{"eq: {bins: {edges: [1, 3, 2]}}", "eq.bins.edges[2]"},
+		{"eq: {bins: {count: 3}}", "eq.bins.preset"},
+		{"eq: {bins: {preset: cubic, count: 3, max: 10}}", "eq.bins.preset"},
+		{"eq: {bins: {preset: linear, max: 10}}", "eq.bins.count"},
+		{"eq: {bins: {preset: octave, count: 1, max: 10}}", "eq.bins.min"},
+		{"eq: {bins: {preset: linear, count: 1, edges: [1, 2]}}", "eq.bins.edges"},
+		{"post: [{type: gain}]", "post[0].gain"},
+		{"post: [{type: gain, gain: 2}, {type: wobble}]", "post[1].type"},
+		{"post: [{type: smooth, release: -1s}]", "post[0].release"},
+		{"displays: []", "displays"},
+		{"displays: [{type: terminal}, {type: lasers}]", "displays[1].type"},
+		{"displays: [{type: terminal}, {type: terminal}]", "displays[1].type"},
+		{"on_display_error: ignore", "on_display_error"},
+		{"displays: [{type: adalight, led: {pixels: 10}}]", "displays[0].device"},
+		{"displays: [{type: tpm2, device: /dev/null}]", "displays[0].led"},
+		{"displays: [{type: terminal, led: {pixels: 10}}]", "displays[0].led"},
+		{"displays: [{type: tpm2, device: x, led: {pixels: 0}}]", "displays[0].led.pixels"},
+		{"displays: [{type: tpm2, device: x, led: {pixels: 1, order: RGX}}]", "displays[0].led.order"},
+		{"displays: [{type: tpm2, device: x, led: {pixels: 8, layout: {type: matrix, width: 4, height: 4}}}]", "displays[0].led.layout"},
+		{"displays: [{type: tpm2, device: x, led: {pixels: 8, layout: {type: matrix, width: 2, height: 2, wiring: zigzag}}}]", "displays[0].led.layout.wiring"},
+		{"displays: [{type: tpm2, device: x, led: {pixels: 8, layout: {type: matrix, width: 2, height: 4, mirror: true}}}]", "displays[0].led.layout.mirror"},
+		{"displays: [{type: tpm2, device: x, led: {pixels: 8, layout: {type: ring, mirror: true}}}]", "displays[0].led.layout.mirror"},
+		{"displays: [{type: tpm2, device: x, led: {pixels: 8, colors: {colors: [red]}}}]", "displays[0].led.colors.colors[0]"},
+		{"displays: [{type: tpm2, device: x, led: {pixels: 8, colors: {type: band}}}]", "displays[0].led.colors.colors"},
+		{"displays: [{type: tpm2, device: x, led: {pixels: 8, brightness: 2}}]", "displays[0].led.brightness"},
+		{"displays: [{type: tpm2, device: x, led: {pixels: 8, power: {channel_ma: 20}}}]", "displays[0].led.power.amps"},
+		{"displays: [{type: tpm2, device: x, led: {pixels: 300, power: {channel_ma: 20, idle_ma: 1, amps: 0.25}}}]", "displays[0].led.power.amps"},
+		{"displays: [{type: e131, led: {pixels: 8}}]", "displays[0].universe"},
+		{"displays: [{type: artnet, channel: 513, led: {pixels: 8}}]", "displays[0].channel"},
+		{"displays: [{type: e131, universe: 1, priority: 255, led: {pixels: 8}}]", "displays[0].priority"},
+		{"displays: [{type: ddp, led: {pixels: 8}}]", "displays[0].address"},
+		{"displays: [{type: wled, address: x, protocol: ddp, led: {pixels: 8}}]", "displays[0].protocol"},
+		{"displays: [{type: opc, channel: 256, led: {pixels: 8}}]", "displays[0].channel"},
+		{"displays: [{type: opc}]", "displays[0].led"},
+		{"displays: [{type: bands}]", "displays[0].address"},
+		{`displays: [{type: bands, listen: [""]}]`, "displays[0].listen[0]"},
+		{"displays: [{type: bands, address: x, led: {pixels: 8}}]", "displays[0].led"},
+		{"displays: [{type: osc}]", "displays[0].address"},
+		{"displays: [{type: osc, address: x, osc: {band: /eq/band}}]", "displays[0].osc.band"},
+		{"displays: [{type: osc, address: x, osc: {level: eq/level}}]", "displays[0].osc.level"},
+		{"displays: [{type: osc, address: x, osc: {rate: -1}}]", "displays[0].osc.rate"},
+		{"displays: [{type: bands, address: x, osc: {}}]", "displays[0].osc"},
+		{"displays: [{type: midi}]", "displays[0].device"},
+		{"displays: [{type: midi, device: x, midi: {file: y.mid}}]", "displays[0].midi.file"},
+		{"displays: [{type: midi, device: x, midi: {channel: 17}}]", "displays[0].midi.channel"},
+		{"displays: [{type: midi, device: x, midi: {controllers: [1, 128]}}]", "displays[0].midi.controllers[1]"},
+		{"displays: [{type: osc, address: x, midi: {}}]", "displays[0].midi"},
+		{"displays: [{type: web, address: x, led: {pixels: 8}}]", "displays[0].led"},
+		{"displays: [{type: mqtt}]", "displays[0].address"},
+		{"displays: [{type: mqtt, address: x, mqtt: {qos: 3}}]", "displays[0].mqtt.qos"},
+		{"displays: [{type: web, mqtt: {}}]", "displays[0].mqtt"},
+	} {
+		_, err := Read(strings.NewReader(tc.yaml))
+		var cerr *Error
+		if assert.ErrorAs(t, err, &cerr, tc.yaml) {
+			assert.Equal(t, tc.key, cerr.Key, tc.yaml)
+		}
+	}
+}
+
+func TestLEDOutput(t *testing.T) {
+	c, err := Read(strings.NewReader(`
+displays:
+  - type: adalight
+    device: /dev/ttyACM0
+    baud: 115200
+    led:
+      pixels: 16
+      order: GRB
+      layout: {type: matrix, width: 4, height: 4, wiring: serpentine}
+      colors: {type: band, palette: ../led/testdata/fire.gpl}
+      gamma: 2.2
+      brightness: 0.5
+      power: {channel_ma: 20, idle_ma: 1, amps: 2, volts: 5}
+`))
+	failIfErr(t, err)
+	m, err := c.Displays[0].LED.Build()
+	failIfErr(t, err)
+
+	assert.Equal(t, led.Strip{Pixels: 16, Order: led.GRB}, m.Strip)
+	assert.Equal(t, led.Matrix{Width: 4, Height: 4, Wiring: led.Serpentine}, m.Layout)
+	assert.Equal(t, led.Color{R: 255}, m.Colors.Color(1, 3, 0))
+	assert.Equal(t, led.NewGamma(2.2), m.Gamma)
+	assert.Equal(t, 0.5, m.Brightness)
+	assert.Equal(t, 2.0, m.Power.Amps)
+}
+
+func TestLEDMirror(t *testing.T) {
+	c, err := Read(strings.NewReader("displays: [{type: tpm2, device: x, led: {pixels: 6, layout: {mirror: true}, colors: {type: solid, colors: ['ff0000']}}}]"))
+	failIfErr(t, err)
+	m, err := c.Displays[0].LED.Build()
+	failIfErr(t, err)
+
+	// one band lit 2 of 3 pixels, growing out from the middle both ways
+	frame := make([]led.Color, 6)
+	m.Layout.Map([]float64{2.0 / 3}, m.Colors, frame)
+	red := led.Color{R: 255}
+	assert.Equal(t, []led.Color{{}, red, red, red, red, {}}, frame)
+}
+
+func TestLEDDefaults(t *testing.T) {
+	c, err := Read(strings.NewReader("displays: [{type: tpm2, device: /dev/ttyUSB0, led: {pixels: 60}}]"))
+	failIfErr(t, err)
+	m, err := c.Displays[0].LED.Build()
+	failIfErr(t, err)
+
+	assert.Equal(t, led.Strip{Pixels: 60, Order: led.RGB}, m.Strip)
+	assert.Equal(t, led.Horizontal{Count: 60}, m.Layout)
+	assert.Equal(t, led.ByLevel{Gradient: led.VU}, m.Colors)
+	assert.Nil(t, m.Gamma)
+	assert.Nil(t, m.Power)
+}
+
+func TestOSCOutput(t *testing.T) {
+	c, err := Read(strings.NewReader(`
+displays:
+  - type: osc
+    address: localhost:9000
+    osc: {level: none, band: "/band/{n}", rate: 30}
+`))
+	failIfErr(t, err)
+	assert.Equal(t, osc.Options{
+		Bands: osc.DefaultBands,
+		Band:  "/band/{n}",
+		Beat:  osc.DefaultBeat,
+		Rate:  30,
+	}, c.Displays[0].OSC.Options())
+
+	var defaults *OSC
+	assert.Equal(t, osc.DefaultLevel, defaults.Options().Level)
+}
+
+func TestMIDIOutput(t *testing.T) {
+	c, err := Read(strings.NewReader(`
+displays:
+  - type: midi
+    midi: {file: out.mid, channel: 2, controllers: [1, 2], curve: 0.5, threshold: 3}
+`))
+	failIfErr(t, err)
+	assert.Equal(t, midi.Options{Channel: 2, Controllers: []int{1, 2}, Curve: 0.5, Threshold: 3}, c.Displays[0].MIDI.Options())
+
+	var defaults *MIDI
+	assert.Equal(t, midi.Options{}, defaults.Options())
+}
+
+func TestMQTTOutput(t *testing.T) {
+	c, err := Read(strings.NewReader(`
+displays:
+  - type: mqtt
+    address: broker.local
+    mqtt: {bands: none, level: home/eq/level, qos: 1, retain: true, username: eq}
+`))
+	failIfErr(t, err)
+	m := c.Displays[0].MQTT
+	assert.Equal(t, mqtt.Options{
+		Level:  "home/eq/level",
+		Beat:   mqtt.DefaultBeat,
+		QoS:    1,
+		Retain: true,
+		Rate:   mqtt.DefaultRate,
+	}, m.Options())
+	assert.Equal(t, mqtt.DialOptions{Broker: "broker.local", ClientID: "led-eq", Username: "eq"}, m.DialOptions("broker.local"))
+
+	var defaults *MQTT
+	assert.Equal(t, mqtt.DefaultBands, defaults.Options().Bands)
+}
+
+func TestUnknownKey(t *testing.T) {
+	_, err := Read(strings.NewReader("eq:\n  normalise: 2\n"))
+	assert.ErrorContains(t, err, "normalise")
+	assert.ErrorContains(t, err, "line 2")
+}
+
+func TestAboveNyquist(t *testing.T) {
+	c := Default()
+	_, err := c.EQ.Build(22_050)
+	var cerr *Error
+	assert.ErrorAs(t, err, &cerr)
+	assert.Equal(t, "eq.bins", cerr.Key)
+}
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	failIfErr(t, os.WriteFile(path, []byte("eq: {normalize: 1}\n"), 0o644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	type result struct {
+		c   Config
+		err error
+	}
+	changes := make(chan result, 10)
+	go Watch(ctx, path, time.Millisecond, func(c Config, err error) {
+		changes <- result{c, err}
+	})
+
+	next := func() result {
+		select {
+		case r := <-changes:
+			return r
+		case <-time.After(time.Second):
+			t.Fatal("no change seen")
+			return result{}
+		}
+	}
+
+	// sizes differ so the change is seen even with a coarse mtime
+	time.Sleep(10 * time.Millisecond)
+	failIfErr(t, os.WriteFile(path, []byte("eq: {normalize: 12}\n"), 0o644))
+	r := next()
+	assert.NoError(t, r.err)
+	assert.Equal(t, 12.0, r.c.EQ.Normalize)
+
+	failIfErr(t, os.WriteFile(path, []byte("eq: {normalize: -1}\n"), 0o644))
+	r = next()
+	assert.Error(t, r.err)
+
+	select {
+	case <-changes:
+		t.Fatal("unchanged file reloaded")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
@@ -0,0 +1,246 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/rabidaudio/led-eq/led"
+)
+
+// LED describes how bands are drawn on a strip, for the LED outputs.
+//
+//	led:
+//	  pixels: 144
+//	  order: GRB
+//	  layout: {type: horizontal, mirror: true}
+//	  colors: {type: level, colors: ["#00ff00", "#ffff00", "#ff0000"]}
+//	  gamma: 2.2
+//	  brightness: 0.5
+//	  power: {channel_ma: 20, idle_ma: 1, amps: 4, volts: 5}
+type LED struct {
+	Pixels int    `yaml:"pixels"`
+	Order  string `yaml:"order"`
+	Layout Layout `yaml:"layout"`
+	Colors Colors `yaml:"colors"`
+	// Gamma corrects for the LEDs' brightness curve; 0 or 1 disables it
+	Gamma float64 `yaml:"gamma"`
+	// Brightness limits every channel to this fraction; 0 is no limit
+	Brightness float64 `yaml:"brightness"`
+	Power      *Power  `yaml:"power"`
+}
+
+// Layout is how bands are arranged on the pixels. Horizontal and ring use
+// all the pixels; mirror shows a horizontal layout twice, growing from the
+// middle.
+type Layout struct {
+	Type string `yaml:"type"`
+	// matrix
+	Width  int    `yaml:"width"`
+	Height int    `yaml:"height"`
+	Wiring string `yaml:"wiring"`
+	// ring
+	Offset int `yaml:"offset"`
+	// horizontal
+	Mirror bool `yaml:"mirror"`
+}
+
+var LayoutTypes = []string{"horizontal", "matrix", "ring"}
+
+var Wirings = []string{"progressive", "serpentine"}
+
+// Colors picks pixel colors from a gradient of Colors or a GIMP palette
+// file, either by band or by level. Rainbow instead spreads the bands
+// around the hue wheel from From to To degrees.
+type Colors struct {
+	Type    string   `yaml:"type"`
+	Colors  []string `yaml:"colors"`
+	Palette string   `yaml:"palette"`
+	From    float64  `yaml:"from"`
+	To      float64  `yaml:"to"`
+}
+
+var ColorTypes = []string{"solid", "band", "level", "rainbow"}
+
+// Power limits the current the strip draws.
+type Power struct {
+	ChannelMA float64 `yaml:"channel_ma"`
+	IdleMA    float64 `yaml:"idle_ma"`
+	Amps      float64 `yaml:"amps"`
+	Volts     float64 `yaml:"volts"`
+}
+
+func (l *LED) validate(key string) error {
+	if l.Pixels < 1 {
+		return keyErr(key+".pixels", "must be at least 1")
+	}
+	if _, err := led.ParseOrder(l.Order); err != nil {
+		return keyErr(key+".order", "%v", err)
+	}
+	if err := l.Layout.validate(key + ".layout"); err != nil {
+		return err
+	}
+	if n := l.Layout.pixels(); n > l.Pixels {
+		return keyErr(key+".layout", "needs %d pixels but the strip has %d", n, l.Pixels)
+	}
+	if err := l.Colors.validate(key + ".colors"); err != nil {
+		return err
+	}
+	if l.Gamma < 0 {
+		return keyErr(key+".gamma", "must be positive")
+	}
+	if l.Brightness < 0 || l.Brightness > 1 {
+		return keyErr(key+".brightness", "must be from 0 to 1")
+	}
+	if p := l.Power; p != nil {
+		if p.ChannelMA <= 0 {
+			return keyErr(key+".power.channel_ma", "must be positive")
+		}
+		if p.IdleMA < 0 {
+			return keyErr(key+".power.idle_ma", "must be positive")
+		}
+		if p.Amps <= 0 {
+			return keyErr(key+".power.amps", "must be positive")
+		}
+		if idle := p.IdleMA * float64(l.Pixels) / 1000; idle > p.Amps {
+			return keyErr(key+".power.amps", "must cover the idle draw of %d pixels (%g A)", l.Pixels, idle)
+		}
+		if p.Volts < 0 {
+			return keyErr(key+".power.volts", "must be positive")
+		}
+	}
+	return nil
+}
+
+func (l *Layout) validate(key string) error {
+	switch l.Type {
+	case "horizontal", "ring":
+	case "matrix":
+		if l.Width < 1 {
+			return keyErr(key+".width", "must be at least 1")
+		}
+		if l.Height < 1 {
+			return keyErr(key+".height", "must be at least 1")
+		}
+		if l.Wiring != "" && !slices.Contains(Wirings, l.Wiring) {
+			return keyErr(key+".wiring", "unknown wiring %q (expected one of %v)", l.Wiring, Wirings)
+		}
+	default:
+		return keyErr(key+".type", "unknown layout %q (expected one of %v)", l.Type, LayoutTypes)
+	}
+	if l.Mirror && l.Type != "horizontal" {
+		return keyErr(key+".mirror", "can't be used with a %s", l.Type)
+	}
+	return nil
+}
+
+// pixels is how many pixels the layout needs, or 0 if it uses the strip.
+func (l *Layout) pixels() int {
+	if l.Type != "matrix" {
+		return 0
+	}
+	return l.Width * l.Height
+}
+
+func (c *Colors) validate(key string) error {
+	if !slices.Contains(ColorTypes, c.Type) {
+		return keyErr(key+".type", "unknown colors %q (expected one of %v)", c.Type, ColorTypes)
+	}
+	if c.Type == "rainbow" {
+		return nil
+	}
+	if c.Palette != "" && len(c.Colors) > 0 {
+		return keyErr(key+".palette", "can't be used with colors")
+	}
+	if c.Palette == "" && len(c.Colors) == 0 {
+		return keyErr(key+".colors", "at least one color (or a palette) is required")
+	}
+	for i, s := range c.Colors {
+		if _, err := led.ParseColor(s); err != nil {
+			return keyErr(fmt.Sprintf("%s.colors[%d]", key, i), "%v", err)
+		}
+	}
+	return nil
+}
+
+// setDefaults fills in settings left out of an LED output: an RGB strip
+// with horizontal bars colored like a level meter.
+func (l *LED) setDefaults() {
+	if l.Order == "" {
+		l.Order = "RGB"
+	}
+	if l.Layout.Type == "" {
+		l.Layout.Type = "horizontal"
+	}
+	if l.Colors.Type == "" {
+		l.Colors.Type = "level"
+		if len(l.Colors.Colors) == 0 && l.Colors.Palette == "" {
+			l.Colors.Colors = []string{"#00ff00", "#ffff00", "#ff0000"}
+		}
+	}
+}
+
+// Build creates the mapper for the strip. Palette files are read here.
+func (l *LED) Build() (*led.Mapper, error) {
+	order, err := led.ParseOrder(l.Order)
+	if err != nil {
+		return nil, err
+	}
+	m := &led.Mapper{
+		Strip:      led.Strip{Pixels: l.Pixels, Order: order},
+		Brightness: l.Brightness,
+	}
+
+	switch l.Layout.Type {
+	case "horizontal":
+		m.Layout = led.Horizontal{Count: l.Pixels}
+		if l.Layout.Mirror {
+			m.Layout = led.Horizontal{Count: l.Pixels / 2}
+		}
+	case "matrix":
+		wiring := led.Progressive
+		if l.Layout.Wiring == "serpentine" {
+			wiring = led.Serpentine
+		}
+		m.Layout = led.Matrix{Width: l.Layout.Width, Height: l.Layout.Height, Wiring: wiring}
+	case "ring":
+		m.Layout = led.Ring{Count: l.Pixels, Offset: l.Layout.Offset}
+	}
+	if l.Layout.Mirror {
+		m.Layout = led.Mirror{Half: m.Layout}
+	}
+
+	if l.Colors.Type == "rainbow" {
+		m.Colors = led.Rainbow{From: l.Colors.From, To: l.Colors.To}
+	} else {
+		var g led.Gradient
+		if l.Colors.Palette != "" {
+			if g, err = led.LoadPalette(l.Colors.Palette); err != nil {
+				return nil, err
+			}
+		} else {
+			colors := make([]led.Color, len(l.Colors.Colors))
+			for i, s := range l.Colors.Colors {
+				if colors[i], err = led.ParseColor(s); err != nil {
+					return nil, err
+				}
+			}
+			g = led.EvenGradient(colors...)
+		}
+		switch l.Colors.Type {
+		case "solid":
+			m.Colors = led.Solid(g.At(0))
+		case "band":
+			m.Colors = led.ByBand{Gradient: g}
+		case "level":
+			m.Colors = led.ByLevel{Gradient: g}
+		}
+	}
+
+	if l.Gamma != 0 && l.Gamma != 1 {
+		m.Gamma = led.NewGamma(l.Gamma)
+	}
+	if p := l.Power; p != nil {
+		m.Power = &led.PowerLimit{ChannelMA: p.ChannelMA, IdleMA: p.IdleMA, Amps: p.Amps, Volts: p.Volts}
+	}
+	return m, nil
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/rabidaudio/led-eq/midi"
+)
+
+// MIDI sets how a midi output maps bands to control changes. The output
+// goes to the display's device, such as a raw MIDI port, or is recorded to
+// a standard MIDI file.
+//
+//	midi:
+//	  channel: 2
+//	  controllers: [20, 21, 22, 23, 24, 25, 26, 27]
+//	  curve: 0.5
+//	  threshold: 2
+type MIDI struct {
+	Channel     int   `yaml:"channel"`
+	Controllers []int `yaml:"controllers"`
+	// Curve is the exponent applied to values; 0 or 1 is linear
+	Curve float64 `yaml:"curve"`
+	// Threshold is how far a controller must move before it is sent again
+	Threshold int `yaml:"threshold"`
+	// File records to a standard MIDI file instead of a device
+	File string `yaml:"file"`
+}
+
+func (m *MIDI) validate(key string) error {
+	if m.Channel < 0 || m.Channel > 16 {
+		return keyErr(key+".channel", "must be from 1 to 16")
+	}
+	for i, c := range m.Controllers {
+		if c < 0 || c > 127 {
+			return keyErr(fmt.Sprintf("%s.controllers[%d]", key, i), "must be from 0 to 127")
+		}
+	}
+	if m.Curve < 0 {
+		return keyErr(key+".curve", "must be positive")
+	}
+	if m.Threshold < 0 || m.Threshold > 127 {
+		return keyErr(key+".threshold", "must be from 0 to 127")
+	}
+	return nil
+}
+
+// Options converts the settings for [midi.NewCC]. A nil MIDI is all
+// defaults.
+func (m *MIDI) Options() midi.Options {
+	if m == nil {
+		return midi.Options{}
+	}
+	return midi.Options{
+		Channel:     m.Channel,
+		Controllers: m.Controllers,
+		Curve:       m.Curve,
+		Threshold:   m.Threshold,
+	}
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"github.com/rabidaudio/led-eq/mqtt"
+)
+
+// MQTT sets the topics an mqtt output publishes to, with the display's
+// address as the broker. Topics left out use the defaults, and none turns
+// a message off.
+//
+//	mqtt:
+//	  bands: none
+//	  level: home/living-room/eq/level
+//	  beat: home/living-room/eq/beat
+//	  qos: 1
+//	  retain: true
+//	  rate: 5
+type MQTT struct {
+	Bands     string `yaml:"bands"`
+	Level     string `yaml:"level"`
+	Beat      string `yaml:"beat"`
+	BeatBands int    `yaml:"beat_bands"`
+	QoS       int    `yaml:"qos"`
+	Retain    bool   `yaml:"retain"`
+	// Rate is the most frames a second to publish; 0 is mqtt.DefaultRate
+	Rate     float64 `yaml:"rate"`
+	ClientID string  `yaml:"client_id"`
+	Username string  `yaml:"username"`
+	Password string  `yaml:"password"`
+}
+
+func (m *MQTT) setDefaults() {
+	for _, t := range []struct {
+		topic *string
+		def   string
+	}{{&m.Bands, mqtt.DefaultBands}, {&m.Level, mqtt.DefaultLevel}, {&m.Beat, mqtt.DefaultBeat}} {
+		if *t.topic == "" {
+			*t.topic = t.def
+		}
+	}
+	if m.Rate == 0 {
+		m.Rate = mqtt.DefaultRate
+	}
+	if m.ClientID == "" {
+		m.ClientID = "led-eq"
+	}
+}
+
+func (m *MQTT) validate(key string) error {
+	if m.QoS < 0 || m.QoS > 2 {
+		return keyErr(key+".qos", "must be 0, 1 or 2")
+	}
+	if m.BeatBands < 0 {
+		return keyErr(key+".beat_bands", "must be positive")
+	}
+	if m.Rate < 0 {
+		return keyErr(key+".rate", "must be positive")
+	}
+	return nil
+}
+
+// Options converts the settings for [mqtt.NewOutput], filling in defaults.
+// A nil MQTT is all defaults.
+func (m *MQTT) Options() mqtt.Options {
+	c := m.withDefaults()
+	topic := func(s string) string {
+		if s == none {
+			return ""
+		}
+		return s
+	}
+	return mqtt.Options{
+		Bands:     topic(c.Bands),
+		Level:     topic(c.Level),
+		Beat:      topic(c.Beat),
+		BeatBands: c.BeatBands,
+		QoS:       byte(c.QoS),
+		Retain:    c.Retain,
+		Rate:      c.Rate,
+	}
+}
+
+// DialOptions are how to connect to the broker at address.
+func (m *MQTT) DialOptions(address string) mqtt.DialOptions {
+	c := m.withDefaults()
+	return mqtt.DialOptions{Broker: address, ClientID: c.ClientID, Username: c.Username, Password: c.Password}
+}
+
+func (m *MQTT) withDefaults() MQTT {
+	var c MQTT
+	if m != nil {
+		c = *m
+	}
+	c.setDefaults()
+	return c
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/rabidaudio/led-eq/osc"
+)
+
+// OSC sets the addresses an osc output sends to. Addresses left out use
+// the defaults, and none turns a message off.
+//
+//	osc:
+//	  bands: /eq/bands
+//	  band: /eq/band/{n}
+//	  level: none
+//	  beat: /eq/beat
+//	  beat_bands: 2
+//	  rate: 30
+type OSC struct {
+	Bands string `yaml:"bands"`
+	// Band is the address of each band, with {n} replaced by its number
+	// from 0
+	Band  string `yaml:"band"`
+	Level string `yaml:"level"`
+	Beat  string `yaml:"beat"`
+	// BeatBands is how many of the lowest bands beats are found in; 0 is all
+	BeatBands int `yaml:"beat_bands"`
+	// Rate is the most frames a second to send; 0 sends every frame
+	Rate float64 `yaml:"rate"`
+}
+
+// none turns off an OSC message
+const none = "none"
+
+func (o *OSC) setDefaults() {
+	for _, a := range []struct {
+		addr *string
+		def  string
+	}{{&o.Bands, osc.DefaultBands}, {&o.Band, osc.DefaultBand}, {&o.Level, osc.DefaultLevel}, {&o.Beat, osc.DefaultBeat}} {
+		if *a.addr == "" {
+			*a.addr = a.def
+		}
+	}
+}
+
+func (o *OSC) validate(key string) error {
+	for _, a := range []struct{ key, addr string }{
+		{"bands", o.Bands}, {"band", o.Band}, {"level", o.Level}, {"beat", o.Beat},
+	} {
+		if a.addr != none && !strings.HasPrefix(a.addr, "/") {
+			return keyErr(key+"."+a.key, "must start with / (or be none)")
+		}
+	}
+	if o.Band != none && !strings.Contains(o.Band, "{n}") {
+		return keyErr(key+".band", "must contain {n}, the band number")
+	}
+	if o.BeatBands < 0 {
+		return keyErr(key+".beat_bands", "must be positive")
+	}
+	if o.Rate < 0 {
+		return keyErr(key+".rate", "must be positive")
+	}
+	return nil
+}
+
+// Options converts the settings for [osc.NewOutput], filling in defaults.
+// A nil OSC is all defaults.
+func (o *OSC) Options() osc.Options {
+	c := OSC{}
+	if o != nil {
+		c = *o
+	}
+	c.setDefaults()
+	o = &c
+	addr := func(s string) string {
+		if s == none {
+			return ""
+		}
+		return s
+	}
+	return osc.Options{
+		Bands:     addr(o.Bands),
+		Band:      addr(o.Band),
+		Level:     addr(o.Level),
+		Beat:      addr(o.Beat),
+		BeatBands: o.BeatBands,
+		Rate:      o.Rate,
+	}
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// Watch polls path every interval and calls onChange with the reloaded
+// config whenever the file is modified, until ctx is done. If the new file
+// doesn't load, onChange gets the error instead, so the caller can keep
+// running with the previous config.
+func Watch(ctx context.Context, path string, interval time.Duration, onChange func(Config, error)) {
+	last, _ := os.Stat(path)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			// probably mid-save by an editor which replaces the file
+			continue
+		}
+		if last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+			continue
+		}
+		last = info
+		onChange(Load(path))
+	}
+}
@@ -1,5 +1,268 @@
 package main
 
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
 type Display interface {
 	Render(values []float64) error
 }
+
+// waiter is a display which finishes closing in the background, so some
+// outputs can send their last messages or write out recordings.
+type waiter interface {
+	Done() <-chan struct{}
+}
+
+// ErrorPolicy decides what a [MultiDisplay] does when one of its displays
+// fails to render.
+type ErrorPolicy int
+
+const (
+	// DropOnError skips the frame for that display and keeps going
+	DropOnError ErrorPolicy = iota
+	// DisableOnError stops sending frames to that display
+	DisableOnError
+	// AbortOnError fails the next Render, stopping the stream
+	AbortOnError
+)
+
+var errorPolicies = map[string]ErrorPolicy{
+	"drop":    DropOnError,
+	"disable": DisableOnError,
+	"abort":   AbortOnError,
+}
+
+// MultiDisplay renders to several displays concurrently. Each display has
+// its own goroutine and queue, so a slow display only falls behind (losing
+// its oldest queued frames) instead of holding up the audio or the others.
+// Displays must not modify the values they are given, as they are shared.
+type MultiDisplay struct {
+	// OnDrop, if set, is called from Render when a display falls behind
+	// and loses a frame. Set it before the first Render.
+	OnDrop func(d Display, n uint64)
+
+	outputs []*output
+	policy  ErrorPolicy
+
+	mu     sync.RWMutex // guards closed against Render
+	closed bool
+	abort  atomic.Pointer[DisplayError]
+	done   chan struct{}
+}
+
+// DisplayError is an error from one of the displays of a [MultiDisplay].
+type DisplayError struct {
+	Index   int
+	Display Display
+	Err     error
+}
+
+func (e *DisplayError) Error() string {
+	return fmt.Sprintf("display %d (%T): %v", e.Index, e.Display, e.Err)
+}
+
+func (e *DisplayError) Unwrap() error {
+	return e.Err
+}
+
+type output struct {
+	d        Display
+	queue    *frameQueue
+	rendered atomic.Uint64
+	dropped  atomic.Uint64
+	disabled atomic.Bool
+
+	mu       sync.Mutex
+	errCount int
+	lastErr  *DisplayError
+}
+
+// DisplayStats are the counters for one display of a [MultiDisplay].
+type DisplayStats struct {
+	Display  Display
+	Rendered uint64
+	Dropped  uint64 // frames lost because the display fell behind
+	Errors   int
+	LastErr  error
+	Disabled bool
+}
+
+// NewMultiDisplay starts rendering to ds. Each display can fall up to
+// queueLen frames behind before frames are dropped.
+func NewMultiDisplay(policy ErrorPolicy, queueLen int, ds ...Display) *MultiDisplay {
+	md := &MultiDisplay{policy: policy, done: make(chan struct{})}
+	var wg sync.WaitGroup
+	for i, d := range ds {
+		o := &output{d: d, queue: newFrameQueue(queueLen)}
+		md.outputs = append(md.outputs, o)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			md.run(i, o)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(md.done)
+	}()
+	return md
+}
+
+var _ Display = (*MultiDisplay)(nil)
+
+func (md *MultiDisplay) run(i int, o *output) {
+	for values := range o.queue.C() {
+		if o.disabled.Load() {
+			continue
+		}
+		err := o.d.Render(values)
+		if err == nil {
+			o.rendered.Add(1)
+			continue
+		}
+		derr := o.fail(i, err)
+		switch md.policy {
+		case DisableOnError:
+			o.disabled.Store(true)
+		case AbortOnError:
+			md.abort.CompareAndSwap(nil, derr)
+		}
+	}
+	if c, ok := o.d.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			o.fail(i, err)
+		}
+	}
+}
+
+func (o *output) fail(i int, err error) *DisplayError {
+	derr := &DisplayError{Index: i, Display: o.d, Err: err}
+	o.mu.Lock()
+	o.errCount++
+	o.lastErr = derr
+	o.mu.Unlock()
+	return derr
+}
+
+// Render queues a copy of values for every display. It only fails if a
+// display failed under [AbortOnError].
+func (md *MultiDisplay) Render(values []float64) error {
+	if err := md.abort.Load(); err != nil {
+		return err
+	}
+	md.mu.RLock()
+	defer md.mu.RUnlock()
+	if md.closed {
+		return nil
+	}
+	frame := append([]float64(nil), values...)
+	for _, o := range md.outputs {
+		if o.disabled.Load() {
+			continue
+		}
+		if o.queue.Push(frame) {
+			o.dropped.Add(1)
+			if md.OnDrop != nil {
+				md.OnDrop(o.d, 1)
+			}
+		}
+	}
+	return nil
+}
+
+// Close stops accepting frames. The displays finish rendering the frames
+// they have queued in the background, since one may be stuck, then those
+// which are an [io.Closer] are closed.
+func (md *MultiDisplay) Close() {
+	md.mu.Lock()
+	defer md.mu.Unlock()
+	if !md.closed {
+		md.closed = true
+		for _, o := range md.outputs {
+			o.queue.Close()
+		}
+	}
+}
+
+// Done is closed once every display has finished and been closed.
+func (md *MultiDisplay) Done() <-chan struct{} {
+	return md.done
+}
+
+// Stats reports the counters of each display.
+func (md *MultiDisplay) Stats() []DisplayStats {
+	stats := make([]DisplayStats, len(md.outputs))
+	for i, o := range md.outputs {
+		o.mu.Lock()
+		stats[i] = DisplayStats{
+			Display:  o.d,
+			Rendered: o.rendered.Load(),
+			Dropped:  o.dropped.Load(),
+			Errors:   o.errCount,
+			Disabled: o.disabled.Load(),
+		}
+		if o.lastErr != nil {
+			stats[i].LastErr = o.lastErr
+		}
+		o.mu.Unlock()
+	}
+	return stats
+}
+
+// Err joins the last error of each display which failed, with how many it
+// had in all.
+func (md *MultiDisplay) Err() error {
+	var errs []error
+	for _, o := range md.outputs {
+		o.mu.Lock()
+		switch {
+		case o.errCount == 1:
+			errs = append(errs, o.lastErr)
+		case o.errCount > 1:
+			errs = append(errs, fmt.Errorf("%w (%d errors)", o.lastErr, o.errCount))
+		}
+		o.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// frameQueue is a bounded queue which drops the oldest frame instead of
+// blocking when full.
+type frameQueue struct {
+	c chan []float64
+}
+
+func newFrameQueue(size int) *frameQueue {
+	return &frameQueue{c: make(chan []float64, max(size, 1))}
+}
+
+// Push adds a frame, returning true if an older frame was dropped to make
+// room. Only one goroutine may push.
+func (q *frameQueue) Push(frame []float64) (dropped bool) {
+	for {
+		select {
+		case q.c <- frame:
+			return dropped
+		default:
+		}
+		// full: discard the oldest, unless the consumer just took it
+		select {
+		case <-q.c:
+			dropped = true
+		default:
+		}
+	}
+}
+
+func (q *frameQueue) C() <-chan []float64 {
+	return q.c
+}
+
+func (q *frameQueue) Close() {
+	close(q.c)
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// syncRecorder is a recordingDisplay which is safe to read while rendering
+type syncRecorder struct {
+	mu sync.Mutex
+	recordingDisplay
+}
+
+func (d *syncRecorder) Render(values []float64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.recordingDisplay.Render(values)
+}
+
+func (d *syncRecorder) count() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.frames)
+}
+
+// blockedDisplay never finishes a render until released
+type blockedDisplay struct {
+	release chan struct{}
+}
+
+func (d *blockedDisplay) Render(values []float64) error {
+	<-d.release
+	return nil
+}
+
+// gatedRecorder is a syncRecorder which waits to be released before
+// recording each frame
+type gatedRecorder struct {
+	syncRecorder
+	release chan struct{}
+}
+
+func (d *gatedRecorder) Render(values []float64) error {
+	<-d.release
+	return d.syncRecorder.Render(values)
+}
+
+// failingDisplay fails every render
+type failingDisplay struct{}
+
+var errFailed = errors.New("failed")
+
+func (failingDisplay) Render(values []float64) error {
+	return errFailed
+}
+
+func TestMultiDisplayFanOut(t *testing.T) {
+	a, b := &syncRecorder{}, &syncRecorder{}
+	md := NewMultiDisplay(DropOnError, 100, a, b)
+	defer md.Close()
+
+	values := []float64{1, 2}
+	for i := range 10 {
+		values[0] = float64(i)
+		assert.NoError(t, md.Render(values))
+	}
+
+	for _, d := range []*syncRecorder{a, b} {
+		assert.Eventually(t, func() bool { return d.count() == 10 }, time.Second, time.Millisecond)
+		d.mu.Lock()
+		assert.Equal(t, []float64{9, 2}, d.frames[9], "each frame is a copy")
+		d.mu.Unlock()
+	}
+}
+
+func TestMultiDisplaySlowDisplay(t *testing.T) {
+	slow := &blockedDisplay{release: make(chan struct{})}
+	fast := &syncRecorder{}
+	md := NewMultiDisplay(DropOnError, 2, slow, fast)
+	defer md.Close()
+
+	start := time.Now()
+	for range 100 {
+		assert.NoError(t, md.Render([]float64{1}))
+		time.Sleep(100 * time.Microsecond) // let the fast display keep up
+	}
+	assert.Less(t, time.Since(start), time.Second, "rendering doesn't block")
+	assert.Eventually(t, func() bool { return fast.count() == 100 }, time.Second, time.Millisecond)
+
+	stats := md.Stats()
+	// one frame stuck rendering, two queued
+	assert.Equal(t, uint64(100-3), stats[0].Dropped)
+	assert.Equal(t, uint64(0), stats[1].Dropped)
+	close(slow.release)
+}
+
+func TestMultiDisplayDropOnError(t *testing.T) {
+	ok := &syncRecorder{}
+	md := NewMultiDisplay(DropOnError, 10, failingDisplay{}, ok)
+	defer md.Close()
+
+	for range 5 {
+		assert.NoError(t, md.Render([]float64{1}))
+	}
+	assert.Eventually(t, func() bool { return md.Stats()[0].Errors == 5 }, time.Second, time.Millisecond)
+	assert.Eventually(t, func() bool { return ok.count() == 5 }, time.Second, time.Millisecond)
+
+	stats := md.Stats()
+	assert.False(t, stats[0].Disabled)
+	assert.ErrorIs(t, stats[0].LastErr, errFailed)
+	assert.Equal(t, 0, stats[1].Errors)
+
+	var derr *DisplayError
+	assert.ErrorAs(t, md.Err(), &derr)
+	assert.Equal(t, 0, derr.Index)
+	assert.ErrorContains(t, md.Err(), "(5 errors)")
+}
+
+func TestMultiDisplayDisableOnError(t *testing.T) {
+	ok := &syncRecorder{}
+	md := NewMultiDisplay(DisableOnError, 1, failingDisplay{}, ok)
+	defer md.Close()
+
+	assert.NoError(t, md.Render([]float64{1}))
+	assert.Eventually(t, func() bool { return md.Stats()[0].Disabled }, time.Second, time.Millisecond)
+	for range 5 {
+		assert.NoError(t, md.Render([]float64{1}))
+		time.Sleep(time.Millisecond)
+	}
+	assert.Eventually(t, func() bool { return ok.count() == 6 }, time.Second, time.Millisecond)
+	assert.Equal(t, 1, md.Stats()[0].Errors)
+}
+
+func TestMultiDisplayAbortOnError(t *testing.T) {
+	md := NewMultiDisplay(AbortOnError, 1, &syncRecorder{}, failingDisplay{})
+	defer md.Close()
+
+	assert.NoError(t, md.Render([]float64{1}))
+	assert.Eventually(t, func() bool {
+		return errors.Is(md.Render([]float64{1}), errFailed)
+	}, time.Second, time.Millisecond)
+}
+
+func TestMultiDisplayClosed(t *testing.T) {
+	d := &syncRecorder{}
+	md := NewMultiDisplay(DropOnError, 1, d)
+	md.Close()
+	md.Close()
+	assert.NoError(t, md.Render([]float64{1}), "ignored once closed")
+	time.Sleep(time.Millisecond)
+	assert.Equal(t, 0, d.count())
+}
+
+func TestFrameQueueDropsOldest(t *testing.T) {
+	q := newFrameQueue(2)
+	assert.False(t, q.Push([]float64{1}))
+	assert.False(t, q.Push([]float64{2}))
+	assert.True(t, q.Push([]float64{3}))
+
+	assert.Equal(t, []float64{2}, <-q.C())
+	assert.Equal(t, []float64{3}, <-q.C())
+}
+
+func TestMultiDisplayLatestValue(t *testing.T) {
+	d := &gatedRecorder{release: make(chan struct{})}
+	md := NewMultiDisplay(DropOnError, 1, d)
+	defer md.Close()
+
+	start := time.Now()
+	for i := range 10 {
+		assert.NoError(t, md.Render([]float64{float64(i)}))
+	}
+	assert.Less(t, time.Since(start), time.Second, "rendering doesn't block")
+	close(d.release)
+	// the display may or may not have taken the first frame before blocking,
+	// but it always ends on the latest
+	assert.Eventually(t, func() bool {
+		stats := md.Stats()
+		return stats[0].Rendered+stats[0].Dropped == 10
+	}, time.Second, time.Millisecond)
+	d.mu.Lock()
+	assert.LessOrEqual(t, len(d.frames), 2)
+	assert.Equal(t, []float64{9}, d.frames[len(d.frames)-1])
+	d.mu.Unlock()
+}
+
+// closingRecorder records whether it was closed
+type closingRecorder struct {
+	syncRecorder
+	closed chan struct{}
+}
+
+func (d *closingRecorder) Close() error {
+	close(d.closed)
+	return nil
+}
+
+func TestDisplaysClosedAfterDraining(t *testing.T) {
+	d := &closingRecorder{closed: make(chan struct{})}
+	md := NewMultiDisplay(DropOnError, 4, d)
+	assert.NoError(t, md.Render([]float64{1}))
+	assert.NoError(t, md.Render([]float64{2}))
+	md.Close()
+	<-d.closed
+	assert.Equal(t, 2, d.count())
+}
+
+func TestDoneAfterClosing(t *testing.T) {
+	a := &closingRecorder{closed: make(chan struct{})}
+	b := &closingRecorder{closed: make(chan struct{})}
+	dd := NewDelayedDisplay(NewMultiDisplay(DropOnError, 4, a, b), time.Millisecond)
+	assert.NoError(t, dd.Render([]float64{1}))
+	dd.Close()
+	select {
+	case <-dd.Done():
+	case <-time.After(time.Second):
+		t.Fatal("not done")
+	}
+	for _, d := range []*closingRecorder{a, b} {
+		select {
+		case <-d.closed:
+		default:
+			t.Fatal("done before the display was closed")
+		}
+	}
+	assert.Equal(t, 1, a.count())
+	assert.Equal(t, 1, b.count())
+}
@@ -0,0 +1,98 @@
+package eq
+
+import (
+	"math"
+	"time"
+)
+
+// Level is the overall loudness of a frame, the RMS of its bands. Values in
+// dB must be converted back with [FromDB] first.
+func Level(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v * v
+	}
+	return math.Sqrt(sum / float64(len(values)))
+}
+
+const (
+	// beatWindow is how much history a beat is compared against
+	beatWindow = time.Second
+	// minBeatInterval stops one hit counting as several beats, allowing up
+	// to 400 BPM
+	minBeatInterval = 150 * time.Millisecond
+	// DefaultSensitivity is how far above the average energy a beat must be
+	DefaultSensitivity = 1.5
+)
+
+// BeatDetector finds beats as sudden rises in the energy of the lowest
+// bands compared to the last second. Like [Level], it needs magnitudes
+// rather than dB.
+type BeatDetector struct {
+	// Bands is how many of the lowest bands to listen to; 0 is all of them
+	Bands int
+	// Sensitivity is how many times the average energy a frame must reach to
+	// be a beat; 0 is [DefaultSensitivity]
+	Sensitivity float64
+	// Floor is the least energy which can be a beat, so silence with a
+	// little noise doesn't trigger beats
+	Floor float64
+
+	history []float64 // ring buffer of recent energies
+	next    int
+	filled  bool
+	holdoff int // frames left before another beat can be found
+	minGap  int
+}
+
+// NewBeatDetector creates a detector for frames spaced period apart,
+// listening to the lowest bands.
+func NewBeatDetector(bands int, period time.Duration) *BeatDetector {
+	return &BeatDetector{
+		Bands:   bands,
+		Floor:   1e-3,
+		history: make([]float64, max(int(beatWindow/period), 2)),
+		minGap:  int(minBeatInterval / period),
+	}
+}
+
+// Detect reports whether the frame is a beat.
+func (b *BeatDetector) Detect(values []float64) bool {
+	if b.Bands > 0 && b.Bands < len(values) {
+		values = values[:b.Bands]
+	}
+	var energy float64
+	for _, v := range values {
+		energy += v * v
+	}
+
+	n := b.next
+	if b.filled {
+		n = len(b.history)
+	}
+	var avg float64
+	for _, e := range b.history[:n] {
+		avg += e
+	}
+	sensitivity := b.Sensitivity
+	if sensitivity == 0 {
+		sensitivity = DefaultSensitivity
+	}
+
+	b.history[b.next] = energy
+	b.next = (b.next + 1) % len(b.history)
+	b.filled = b.filled || b.next == 0
+
+	if b.holdoff > 0 {
+		b.holdoff--
+		return false
+	}
+	if n == 0 || energy < b.Floor || energy <= sensitivity*avg/float64(n) {
+		return false
+	}
+	b.holdoff = b.minGap
+	return true
+}
@@ -0,0 +1,64 @@
+package eq
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevel(t *testing.T) {
+	assert.Equal(t, 0.0, Level(nil))
+	assert.Equal(t, 0.5, Level([]float64{0.5, -0.5}))
+	assert.InDelta(t, math.Sqrt(0.5), Level([]float64{1, 0}), 1e-9)
+}
+
+func TestLevelInDB(t *testing.T) {
+	values := []float64{math.Inf(-1), 0, -6}
+	FromDB(values)
+	assert.Equal(t, 0.0, values[0], "silence")
+	assert.Equal(t, 1.0, values[1])
+	assert.InDelta(t, 0.5, values[2], 0.01)
+	assert.InDelta(t, math.Sqrt(1.25/3), Level(values), 0.01)
+}
+
+func TestBeatDetector(t *testing.T) {
+	// 10 frames a second, kicks every half second in the first band over a
+	// steady hum, with the upper band ignored
+	b := NewBeatDetector(1, 100*time.Millisecond)
+	var beats []int
+	for i := range 30 {
+		v := []float64{0.2, float64(i % 2)}
+		if i%5 == 4 {
+			v[0] = 1
+		}
+		if b.Detect(v) {
+			beats = append(beats, i)
+		}
+	}
+	assert.Equal(t, []int{4, 9, 14, 19, 24, 29}, beats)
+}
+
+func TestBeatDetectorHoldoff(t *testing.T) {
+	// a hit lasting several frames is one beat
+	b := NewBeatDetector(0, 10*time.Millisecond)
+	var beats int
+	for i := range 200 {
+		level := 0.1
+		if i >= 100 && i < 110 {
+			level = 1 + float64(i-100)
+		}
+		if b.Detect([]float64{level}) {
+			beats++
+		}
+	}
+	assert.Equal(t, 1, beats)
+}
+
+func TestBeatDetectorSilence(t *testing.T) {
+	b := NewBeatDetector(0, 10*time.Millisecond)
+	for i := range 100 {
+		assert.False(t, b.Detect([]float64{1e-4 * float64(i%2)}))
+	}
+}
@@ -52,6 +52,28 @@ func TestExponentialBins(t *testing.T) {
 	assert.InEpsilon(t, -1.0, y, 0.0001)
 }
 
+func TestOctaveBins(t *testing.T) {
+	b := OctaveBins(25, 200, 1)
+
+	assert.Equal(t, 3, b.Len())
+	x, y := b.Bounds(0)
+	assert.InEpsilon(t, 25.0, x, 0.0001)
+	assert.InEpsilon(t, 50.0, y, 0.0001)
+
+	x, y = b.Bounds(2)
+	assert.InEpsilon(t, 100.0, x, 0.0001)
+	assert.InEpsilon(t, 200.0, y, 0.0001)
+
+	// third octaves, last one clipped
+	b = OctaveBins(20, 20_000, 3)
+	assert.Equal(t, 30, b.Len())
+	x, y = b.Bounds(1)
+	assert.InEpsilon(t, 25.198, x, 0.0001)
+	assert.InEpsilon(t, 31.748, y, 0.0001)
+	_, y = b.Bounds(29)
+	assert.Equal(t, 20_000.0, y)
+}
+
 func TestArbitraryBins(t *testing.T) {
 	b := ArbitraryBins(20, 100, 250, 500, 1000, 2500, 20_000)
 
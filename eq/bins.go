@@ -39,6 +39,19 @@ func ExponentialBins(start, stop float64, len int) Bins {
 	return b
 }
 
+// OctaveBins splits start..stop into bands 1/perOctave of an octave wide.
+// The last band is cut short at stop if the range isn't a whole number of
+// bands.
+func OctaveBins(start, stop float64, perOctave int) Bins {
+	n := int(math.Ceil(math.Log2(stop/start)*float64(perOctave) - 1e-9))
+	b := make([]float64, n+1)
+	for i := range n + 1 {
+		b[i] = start * math.Pow(2, float64(i)/float64(perOctave))
+	}
+	b[n] = stop
+	return b
+}
+
 func ArbitraryBins(bounds ...float64) Bins {
 	return bounds
 }
@@ -50,6 +50,7 @@ func Default() EQ {
 		SampleRate: 44100,
 		N:          2048,
 		OutBins:    ExponentialBins(20, 20_000, 16),
+		Normalize:  1,
 	}
 }
 
@@ -87,11 +88,14 @@ func (eq *EQ) Compute(samples []float64, out []float64) {
 	// re-bin
 	src := LinearBins(0, float64(eq.SampleRate), eq.N)
 	resample(src, eq.OutBins, fft, out)
-	if eq.Normalize == 0 {
-		eq.Normalize = 1
+	// zero is unset; eq isn't written to, as the gain may be read while
+	// computing
+	norm := eq.Normalize
+	if norm == 0 {
+		norm = 1
 	}
 	for i := range out {
-		out[i] *= eq.Normalize
+		out[i] *= norm
 	}
 	if eq.OutputDB {
 		ToDB(out)
@@ -131,3 +135,10 @@ func ToDB(samples []float64) {
 		samples[i] = db(samples[i])
 	}
 }
+
+// FromDB converts values from dB back to magnitudes, undoing [ToDB].
+func FromDB(values []float64) {
+	for i := range values {
+		values[i] = math.Pow(10, values[i]/20)
+	}
+}
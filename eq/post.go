@@ -0,0 +1,87 @@
+package eq
+
+import (
+	"math"
+	"time"
+)
+
+// Stage post-processes computed band values in place, once per frame.
+type Stage interface {
+	Process(values []float64)
+}
+
+// Chain runs each stage in order.
+type Chain []Stage
+
+func (c Chain) Process(values []float64) {
+	for _, s := range c {
+		s.Process(values)
+	}
+}
+
+// Gain scales every band.
+type Gain float64
+
+func (g Gain) Process(values []float64) {
+	for i := range values {
+		values[i] *= float64(g)
+	}
+}
+
+// Clip limits bands to [Min, Max].
+type Clip struct {
+	Min, Max float64
+}
+
+func (c Clip) Process(values []float64) {
+	for i := range values {
+		values[i] = min(max(values[i], c.Min), c.Max)
+	}
+}
+
+// Smooth is a per-band envelope follower. Rising values move towards the
+// input with time constant Attack and falling values with Release, so bars
+// can jump up quickly but fall slowly.
+type Smooth struct {
+	attack, release float64 // per-frame coefficients
+	prev            []float64
+}
+
+// NewSmooth creates a [Smooth] stage for frames spaced period apart.
+// A zero time constant passes values through unchanged.
+func NewSmooth(attack, release, period time.Duration) *Smooth {
+	return &Smooth{
+		attack:  coefficient(attack, period),
+		release: coefficient(release, period),
+	}
+}
+
+// coefficient is the fraction of the remaining distance covered in one
+// frame by an exponential with time constant tau
+func coefficient(tau, period time.Duration) float64 {
+	if tau <= 0 {
+		return 1
+	}
+	return 1 - math.Exp(-period.Seconds()/tau.Seconds())
+}
+
+func (s *Smooth) Process(values []float64) {
+	if len(s.prev) != len(values) {
+		s.prev = append(s.prev[:0], values...)
+		return
+	}
+	for i, v := range values {
+		// an infinite value, like silence in dB, can't be followed, and
+		// would leave NaN behind
+		if math.IsInf(v, 0) || math.IsNaN(v) || math.IsInf(s.prev[i], 0) || math.IsNaN(s.prev[i]) {
+			s.prev[i] = v
+			continue
+		}
+		c := s.release
+		if v > s.prev[i] {
+			c = s.attack
+		}
+		s.prev[i] += c * (v - s.prev[i])
+		values[i] = s.prev[i]
+	}
+}
@@ -0,0 +1,58 @@
+package eq
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGainAndClip(t *testing.T) {
+	v := []float64{-1, 0.25, 0.5, 2}
+	Chain{Gain(2), Clip{Min: 0, Max: 1}}.Process(v)
+	assert.Equal(t, []float64{0, 0.5, 1, 1}, v)
+}
+
+func TestSmooth(t *testing.T) {
+	s := NewSmooth(0, 100*time.Millisecond, 100*time.Millisecond)
+
+	v := []float64{1, 0}
+	s.Process(v)
+	assert.Equal(t, []float64{1, 0}, v, "first frame passes through")
+
+	v = []float64{0, 1}
+	s.Process(v)
+	// instant attack, release covers 1-1/e of the distance per time constant
+	assert.InDelta(t, math.Exp(-1), v[0], 0.0001)
+	assert.Equal(t, 1.0, v[1])
+
+	v = []float64{0, 1}
+	s.Process(v)
+	assert.InDelta(t, math.Exp(-2), v[0], 0.0001)
+}
+
+func TestSmoothSilenceInDB(t *testing.T) {
+	s := NewSmooth(0, 100*time.Millisecond, 100*time.Millisecond)
+	s.Process([]float64{-6})
+
+	v := []float64{math.Inf(-1)}
+	s.Process(v)
+	assert.Equal(t, math.Inf(-1), v[0])
+
+	v = []float64{-12}
+	s.Process(v)
+	assert.Equal(t, -12.0, v[0], "picks up again after silence")
+	v = []float64{-24}
+	s.Process(v)
+	assert.InDelta(t, -12-12*(1-math.Exp(-1)), v[0], 0.0001)
+}
+
+func TestSmoothResize(t *testing.T) {
+	s := NewSmooth(time.Second, time.Second, time.Millisecond)
+	s.Process([]float64{1, 1})
+
+	v := []float64{0.5, 0.5, 0.5}
+	s.Process(v)
+	assert.Equal(t, []float64{0.5, 0.5, 0.5}, v, "restarts when the bins change")
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+
+	"github.com/faiface/beep"
+	"github.com/rabidaudio/led-eq/frames"
+	"github.com/rabidaudio/led-eq/resample"
+	"github.com/rabidaudio/led-eq/wav"
+)
+
+// runExport renders the band data of a wav file to a frames file, for
+// playing back later without the audio.
+func runExport(args []string) error {
+	fs := newFlagSet("export")
+	ef := addEQFlags(fs)
+	output := fs.String("o", "", "output file")
+	formatName := fs.String("format", "", "csv, ndjson or bin (default: from the -o extension)")
+	rate := fs.Int("rate", analysisRate, "resample the input to this rate before analysis, 0 to disable")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := ef.validate(); err != nil {
+		return err
+	}
+	if *rate < 0 {
+		return invalid(fs, "-rate must be positive")
+	}
+	if *output == "" {
+		return invalid(fs, "-o is required")
+	}
+	if fs.NArg() > 1 {
+		return invalid(fs, "only one input can be given")
+	}
+	var format frames.Format
+	var err error
+	if *formatName != "" {
+		format, err = frames.ParseFormat(*formatName)
+	} else {
+		format, err = frames.FormatForPath(*output)
+	}
+	if err != nil {
+		return invalid(fs, "%v", err)
+	}
+
+	var wv *wav.WavReader
+	if path := fs.Arg(0); path != "" && path != "-" {
+		wf, err := wav.OpenWavFile(path)
+		if err != nil {
+			return err
+		}
+		defer wf.Close()
+		wv = wf.WavReader
+	} else {
+		wv, err = wav.OpenWav(os.Stdin)
+		if err != nil {
+			return err
+		}
+	}
+
+	// the same analysis as play and analyze, so the frames match theirs
+	var src frames.MonoReader = wv
+	sampleRate := wv.SampleRate()
+	if *rate != 0 && sampleRate != *rate {
+		src = &monoStreamer{s: resample.New(resample.DefaultQuality, sampleRate, *rate, wv)}
+		sampleRate = *rate
+	}
+	e, err := ef.eq(sampleRate)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	bw := bufio.NewWriter(out)
+
+	h := frames.Header{
+		SampleRate: e.SampleRate,
+		N:          e.N,
+		Hop:        frames.HopForFrameRate(e.SampleRate, ef.fps),
+		Bins:       e.OutBins,
+	}
+	fw, err := frames.NewWriter(bw, format, h)
+	if err != nil {
+		return err
+	}
+	if err := frames.Export(src, &e, h.Hop, fw); err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// monoStreamer reads a streamer, such as a resampler, a channel at a time.
+type monoStreamer struct {
+	s   beep.Streamer
+	buf [][2]float64
+}
+
+func (m *monoStreamer) ReadMono(p []float64) (int, error) {
+	if len(m.buf) < len(p) {
+		m.buf = make([][2]float64, len(p))
+	}
+	n, ok := m.s.Stream(m.buf[:len(p)])
+	wav.ToMono(m.buf[:n], p)
+	if !ok {
+		if err := m.s.Err(); err != nil {
+			return n, err
+		}
+		return n, io.EOF
+	}
+	return n, nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/rabidaudio/led-eq/eq"
+	"github.com/rabidaudio/led-eq/frames"
+	"github.com/rabidaudio/led-eq/resample"
+	"github.com/stretchr/testify/assert"
+)
+
+// capture keeps the frames written to it.
+type capture struct {
+	frames []frames.Frame
+}
+
+func (c *capture) WriteFrame(f frames.Frame) error {
+	c.frames = append(c.frames, f)
+	return nil
+}
+
+func (c *capture) Flush() error {
+	return nil
+}
+
+func TestExportResampled(t *testing.T) {
+	src := &monoStreamer{s: resample.New(resample.DefaultQuality, 44_100, 48_000, sine(1000, 44_100, 44_100))}
+	e := eq.New(48_000, 1024, 8)
+	var c capture
+	failIfErr(t, frames.Export(src, &e, 800, &c))
+
+	// a second at the new rate, less the last partial window
+	assert.InDelta(t, (48_000-1024)/800+1, len(c.frames), 1)
+	loudest := 0
+	for i, v := range c.frames[len(c.frames)/2].Values {
+		if v > c.frames[len(c.frames)/2].Values[loudest] {
+			loudest = i
+		}
+	}
+	lo, hi := e.OutBins.Bounds(loudest)
+	assert.True(t, lo <= 1000 && hi > 1000, "the tone is still at 1 kHz")
+}
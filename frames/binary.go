@@ -0,0 +1,131 @@
+package frames
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// The binary format is little-endian throughout:
+//
+//	header: "LEQF" | version u8 | sample rate u32 | N u32 | hop u32 |
+//	        edge count u16 | edges f32...
+//	frame:  sample u64 | values f32...
+//
+// Frames are fixed size (8 + 4*bins bytes) so a reader can seek directly
+// to a frame.
+var binaryMagic = [4]byte{'L', 'E', 'Q', 'F'}
+
+const binaryVersion = 1
+
+type binaryWriter struct {
+	w   *bufio.Writer
+	h   Header
+	buf []byte
+}
+
+func newBinaryWriter(w io.Writer, h Header) (*binaryWriter, error) {
+	if len(h.Bins) > math.MaxUint16 {
+		return nil, fmt.Errorf("frames: too many bins (%d)", h.Bins.Len())
+	}
+	bw := &binaryWriter{w: bufio.NewWriter(w), h: h}
+	hdr := make([]byte, 0, binaryHeaderLen(h))
+	hdr = append(hdr, binaryMagic[:]...)
+	hdr = append(hdr, binaryVersion)
+	hdr = binary.LittleEndian.AppendUint32(hdr, uint32(h.SampleRate))
+	hdr = binary.LittleEndian.AppendUint32(hdr, uint32(h.N))
+	hdr = binary.LittleEndian.AppendUint32(hdr, uint32(h.Hop))
+	hdr = binary.LittleEndian.AppendUint16(hdr, uint16(len(h.Bins)))
+	for _, b := range h.Bins {
+		hdr = binary.LittleEndian.AppendUint32(hdr, math.Float32bits(float32(b)))
+	}
+	if _, err := bw.w.Write(hdr); err != nil {
+		return nil, err
+	}
+	bw.buf = make([]byte, binaryFrameLen(h))
+	return bw, nil
+}
+
+func binaryHeaderLen(h Header) int {
+	return 4 + 1 + 3*4 + 2 + 4*len(h.Bins)
+}
+
+func binaryFrameLen(h Header) int {
+	return 8 + 4*h.Bins.Len()
+}
+
+func (bw *binaryWriter) WriteFrame(f Frame) error {
+	if len(f.Values) != bw.h.Bins.Len() {
+		return fmt.Errorf("frames: expected %d values but got %d", bw.h.Bins.Len(), len(f.Values))
+	}
+	binary.LittleEndian.PutUint64(bw.buf, uint64(f.Sample))
+	for i, v := range f.Values {
+		binary.LittleEndian.PutUint32(bw.buf[8+4*i:], math.Float32bits(float32(v)))
+	}
+	_, err := bw.w.Write(bw.buf)
+	return err
+}
+
+func (bw *binaryWriter) Flush() error {
+	return bw.w.Flush()
+}
+
+type binaryReader struct {
+	r   *bufio.Reader
+	h   Header
+	buf []byte
+}
+
+func newBinaryReader(r io.Reader) (*binaryReader, error) {
+	br := &binaryReader{r: bufio.NewReader(r)}
+	fixed := make([]byte, 4+1+3*4+2)
+	if _, err := io.ReadFull(br.r, fixed); err != nil {
+		return nil, fmt.Errorf("frames: reading binary header: %w", err)
+	}
+	if [4]byte(fixed[:4]) != binaryMagic {
+		return nil, fmt.Errorf("frames: not a led-eq binary file")
+	}
+	if fixed[4] != binaryVersion {
+		return nil, fmt.Errorf("frames: unsupported binary version %d", fixed[4])
+	}
+	br.h.SampleRate = int(binary.LittleEndian.Uint32(fixed[5:]))
+	br.h.N = int(binary.LittleEndian.Uint32(fixed[9:]))
+	br.h.Hop = int(binary.LittleEndian.Uint32(fixed[13:]))
+	edges := make([]byte, 4*int(binary.LittleEndian.Uint16(fixed[17:])))
+	if _, err := io.ReadFull(br.r, edges); err != nil {
+		return nil, fmt.Errorf("frames: reading binary header: %w", err)
+	}
+	br.h.Bins = make([]float64, len(edges)/4)
+	for i := range br.h.Bins {
+		br.h.Bins[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(edges[4*i:])))
+	}
+	if err := br.h.validate(); err != nil {
+		return nil, err
+	}
+	br.buf = make([]byte, binaryFrameLen(br.h))
+	return br, nil
+}
+
+func (br *binaryReader) Header() Header {
+	return br.h
+}
+
+func (br *binaryReader) ReadFrame() (Frame, error) {
+	if _, err := io.ReadFull(br.r, br.buf); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return Frame{}, fmt.Errorf("frames: truncated frame: %w", err)
+		}
+		return Frame{}, err
+	}
+	f := Frame{
+		Sample: int64(binary.LittleEndian.Uint64(br.buf)),
+		Values: make([]float64, br.h.Bins.Len()),
+	}
+	for i := range f.Values {
+		f.Values[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(br.buf[8+4*i:])))
+	}
+	return f, nil
+}
@@ -0,0 +1,144 @@
+package frames
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// The CSV format starts with a comment line holding the header, followed by
+// a row of column names and then one row per frame:
+//
+//	# led-eq sample_rate=48000 n=1024 hop=800 bins=50;100;200
+//	time,sample,50-100,100-200
+//	0.016667,800,0.25,0.5
+const csvMagic = "# led-eq"
+
+type csvWriter struct {
+	w   *csv.Writer
+	h   Header
+	rec []string
+}
+
+func newCSVWriter(w io.Writer, h Header) (*csvWriter, error) {
+	edges := make([]string, len(h.Bins))
+	for i, b := range h.Bins {
+		edges[i] = formatFloat(b)
+	}
+	_, err := fmt.Fprintf(w, "%s sample_rate=%d n=%d hop=%d bins=%s\n",
+		csvMagic, h.SampleRate, h.N, h.Hop, strings.Join(edges, ";"))
+	if err != nil {
+		return nil, err
+	}
+
+	cw := &csvWriter{w: csv.NewWriter(w), h: h}
+	cols := []string{"time", "sample"}
+	for i := range h.Bins.Len() {
+		lo, hi := h.Bins.Bounds(i)
+		cols = append(cols, formatFloat(lo)+"-"+formatFloat(hi))
+	}
+	if err := cw.w.Write(cols); err != nil {
+		return nil, err
+	}
+	cw.rec = make([]string, len(cols))
+	return cw, nil
+}
+
+func (cw *csvWriter) WriteFrame(f Frame) error {
+	if len(f.Values) != cw.h.Bins.Len() {
+		return fmt.Errorf("frames: expected %d values but got %d", cw.h.Bins.Len(), len(f.Values))
+	}
+	cw.rec[0] = strconv.FormatFloat(cw.h.Time(f.Sample).Seconds(), 'f', 6, 64)
+	cw.rec[1] = strconv.FormatInt(f.Sample, 10)
+	for i, v := range f.Values {
+		cw.rec[i+2] = formatFloat(v)
+	}
+	return cw.w.Write(cw.rec)
+}
+
+func (cw *csvWriter) Flush() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+type csvReader struct {
+	r *csv.Reader
+	h Header
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	br := bufio.NewReader(r)
+	line, err := br.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("frames: reading csv header: %w", err)
+	}
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, csvMagic+" ") {
+		return nil, fmt.Errorf("frames: not a led-eq csv file")
+	}
+	var h Header
+	for _, kv := range strings.Fields(strings.TrimPrefix(line, csvMagic)) {
+		k, v, _ := strings.Cut(kv, "=")
+		switch k {
+		case "sample_rate":
+			h.SampleRate, err = strconv.Atoi(v)
+		case "n":
+			h.N, err = strconv.Atoi(v)
+		case "hop":
+			h.Hop, err = strconv.Atoi(v)
+		case "bins":
+			for _, b := range strings.Split(v, ";") {
+				var f float64
+				f, err = strconv.ParseFloat(b, 64)
+				if err != nil {
+					break
+				}
+				h.Bins = append(h.Bins, f)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("frames: invalid csv header %q: %w", kv, err)
+		}
+	}
+	if err := h.validate(); err != nil {
+		return nil, err
+	}
+
+	cr := &csvReader{r: csv.NewReader(br), h: h}
+	cr.r.FieldsPerRecord = 2 + h.Bins.Len()
+	cr.r.ReuseRecord = true
+	if _, err := cr.r.Read(); err != nil { // column names
+		return nil, fmt.Errorf("frames: reading csv columns: %w", err)
+	}
+	return cr, nil
+}
+
+func (cr *csvReader) Header() Header {
+	return cr.h
+}
+
+func (cr *csvReader) ReadFrame() (f Frame, err error) {
+	rec, err := cr.r.Read()
+	if err != nil {
+		return f, err
+	}
+	f.Sample, err = strconv.ParseInt(rec[1], 10, 64)
+	if err != nil {
+		return f, fmt.Errorf("frames: invalid sample %q: %w", rec[1], err)
+	}
+	f.Values = make([]float64, len(rec)-2)
+	for i := range f.Values {
+		f.Values[i], err = strconv.ParseFloat(rec[i+2], 64)
+		if err != nil {
+			return f, fmt.Errorf("frames: invalid value %q: %w", rec[i+2], err)
+		}
+	}
+	return f, nil
+}
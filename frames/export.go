@@ -0,0 +1,92 @@
+package frames
+
+import (
+	"errors"
+	"io"
+
+	"github.com/rabidaudio/led-eq/eq"
+)
+
+type MonoReader interface {
+	ReadMono(p []float64) (n int, err error)
+}
+
+// HopForFrameRate is the number of samples between frames to produce fps
+// frames per second.
+func HopForFrameRate(sampleRate int, fps float64) int {
+	hop := int(float64(sampleRate)/fps + 0.5)
+	return max(hop, 1)
+}
+
+// Export runs the whole of r through e as fast as possible, writing a frame
+// every hop samples. Each frame is computed over the N samples starting at
+// its position, so frames overlap when hop < N. The trailing samples which
+// don't fill a window are dropped.
+func Export(r MonoReader, e *eq.EQ, hop int, w Writer) error {
+	window := make([]float64, e.N)
+	out := make([]float64, e.OutBins.Len())
+	chunk := make([]float64, max(hop, e.N))
+
+	filled := 0 // valid samples at the start of window
+	skip := 0   // samples to discard before the next window (hop > N)
+	var pos int64
+	for {
+		want := e.N - filled
+		if skip > 0 {
+			want = min(skip, len(chunk))
+		}
+		n, err := readFull(r, chunk[:want])
+		if skip > 0 {
+			skip -= n
+			pos += int64(n)
+		} else {
+			copy(window[filled:], chunk[:n])
+			filled += n
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
+		}
+		if filled < e.N {
+			continue
+		}
+
+		for i := range out {
+			out[i] = 0
+		}
+		e.Compute(window, out)
+		if err := w.WriteFrame(Frame{Sample: pos, Values: out}); err != nil {
+			return err
+		}
+
+		// advance
+		if hop < e.N {
+			copy(window, window[hop:])
+			filled = e.N - hop
+		} else {
+			filled = 0
+			skip = hop - e.N
+		}
+		pos += int64(min(hop, e.N))
+	}
+	return w.Flush()
+}
+
+// readFull reads until p is full, returning io.EOF if the reader ran out
+// first
+func readFull(r MonoReader, p []float64) (n int, err error) {
+	for n < len(p) && err == nil {
+		var nn int
+		nn, err = r.ReadMono(p[n:])
+		n += nn
+		if nn == 0 && err == nil {
+			err = io.ErrNoProgress
+		}
+	}
+	if n == len(p) && errors.Is(err, io.EOF) {
+		err = nil
+	}
+	return n, err
+}
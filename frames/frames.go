@@ -0,0 +1,146 @@
+package frames
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rabidaudio/led-eq/eq"
+)
+
+// Header describes the analysis which produced a recording, so the frames
+// can be played back in sync with the source audio.
+type Header struct {
+	SampleRate int
+	// N is the FFT size (window length) of each frame
+	N int
+	// Hop is the number of samples between the start of consecutive frames
+	Hop  int
+	Bins eq.Bins
+}
+
+// Time converts a sample position to a timestamp.
+func (h Header) Time(sample int64) time.Duration {
+	return time.Duration(sample) * time.Second / time.Duration(h.SampleRate)
+}
+
+// Sample converts a timestamp to the nearest sample position.
+func (h Header) Sample(t time.Duration) int64 {
+	return int64((t*time.Duration(h.SampleRate) + time.Second/2) / time.Second)
+}
+
+func (h Header) validate() error {
+	if h.SampleRate <= 0 || h.N <= 0 || h.Hop <= 0 {
+		return fmt.Errorf("frames: invalid header %+v", h)
+	}
+	if h.Bins.Len() < 1 {
+		return fmt.Errorf("frames: header must have at least one bin")
+	}
+	return nil
+}
+
+// Frame is a single set of band values. Sample is the position of the start
+// of the analysis window in the source.
+type Frame struct {
+	Sample int64
+	Values []float64
+}
+
+type Format int
+
+const (
+	CSV Format = iota
+	NDJSON
+	Binary
+)
+
+func (f Format) String() string {
+	switch f {
+	case CSV:
+		return "csv"
+	case NDJSON:
+		return "ndjson"
+	case Binary:
+		return "bin"
+	}
+	return fmt.Sprintf("Format(%d)", int(f))
+}
+
+// ParseFormat accepts a format name (csv, ndjson, bin)
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "csv":
+		return CSV, nil
+	case "ndjson", "jsonl", "json":
+		return NDJSON, nil
+	case "bin", "binary":
+		return Binary, nil
+	}
+	return 0, fmt.Errorf("frames: unknown format %q", s)
+}
+
+// FormatForPath guesses the format from a file extension.
+func FormatForPath(path string) (Format, error) {
+	ext := strings.TrimPrefix(filepath.Ext(path), ".")
+	return ParseFormat(ext)
+}
+
+type Writer interface {
+	WriteFrame(f Frame) error
+	// Flush writes any buffered data to the underlying writer
+	Flush() error
+}
+
+// NewWriter writes the header for h to w in the given format and returns a
+// [Writer] for the frames.
+func NewWriter(w io.Writer, format Format, h Header) (Writer, error) {
+	if err := h.validate(); err != nil {
+		return nil, err
+	}
+	switch format {
+	case CSV:
+		return newCSVWriter(w, h)
+	case NDJSON:
+		return newNDJSONWriter(w, h)
+	case Binary:
+		return newBinaryWriter(w, h)
+	}
+	return nil, fmt.Errorf("frames: unknown format %v", format)
+}
+
+type Reader interface {
+	Header() Header
+	// ReadFrame returns the next frame, or io.EOF at the end of the recording
+	ReadFrame() (Frame, error)
+}
+
+// NewReader reads the header from r and returns a [Reader] for its frames.
+func NewReader(r io.Reader, format Format) (Reader, error) {
+	switch format {
+	case CSV:
+		return newCSVReader(r)
+	case NDJSON:
+		return newNDJSONReader(r)
+	case Binary:
+		return newBinaryReader(r)
+	}
+	return nil, fmt.Errorf("frames: unknown format %v", format)
+}
+
+// ReadAll reads the remaining frames from r.
+func ReadAll(r Reader) ([]Frame, error) {
+	var frames []Frame
+	for {
+		f, err := r.ReadFrame()
+		if errors.Is(err, io.EOF) {
+			return frames, nil
+		}
+		if err != nil {
+			return frames, err
+		}
+		frames = append(frames, f)
+	}
+}
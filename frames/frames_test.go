@@ -0,0 +1,227 @@
+package frames
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rabidaudio/led-eq/eq"
+	"github.com/rabidaudio/led-eq/wav"
+	"github.com/stretchr/testify/assert"
+)
+
+func failIfErr(t *testing.T, err error) {
+	assert.NoError(t, err)
+	if err != nil {
+		t.FailNow()
+	}
+}
+
+var testHeader = Header{SampleRate: 48_000, N: 1024, Hop: 800, Bins: eq.ArbitraryBins(50, 100, 200)}
+
+var testFrames = []Frame{
+	{Sample: 0, Values: []float64{0.25, 0.5}},
+	{Sample: 800, Values: []float64{1, 0.125}},
+}
+
+func writeAll(t *testing.T, format Format) []byte {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, format, testHeader)
+	failIfErr(t, err)
+	for _, f := range testFrames {
+		failIfErr(t, w.WriteFrame(f))
+	}
+	failIfErr(t, w.Flush())
+	return buf.Bytes()
+}
+
+func TestHeaderTime(t *testing.T) {
+	assert.Equal(t, 1*time.Second, testHeader.Time(48_000))
+	assert.Equal(t, 500*time.Microsecond, testHeader.Time(24))
+	assert.Equal(t, int64(24), testHeader.Sample(500*time.Microsecond))
+}
+
+func TestFormatForPath(t *testing.T) {
+	f, err := FormatForPath("show/song.ndjson")
+	assert.NoError(t, err)
+	assert.Equal(t, NDJSON, f)
+
+	f, err = FormatForPath("song.BIN")
+	assert.NoError(t, err)
+	assert.Equal(t, Binary, f)
+
+	_, err = FormatForPath("song.wav")
+	assert.Error(t, err)
+}
+
+func TestWriteCSV(t *testing.T) {
+	assert.Equal(t, "# led-eq sample_rate=48000 n=1024 hop=800 bins=50;100;200\n"+
+		"time,sample,50-100,100-200\n"+
+		"0.000000,0,0.25,0.5\n"+
+		"0.016667,800,1,0.125\n", string(writeAll(t, CSV)))
+}
+
+func TestWriteNDJSON(t *testing.T) {
+	assert.Equal(t, `{"sample_rate":48000,"n":1024,"hop":800,"bins":[50,100,200]}`+"\n"+
+		`{"t":0,"sample":0,"values":[0.25,0.5]}`+"\n"+
+		`{"t":0.016666666,"sample":800,"values":[1,0.125]}`+"\n", string(writeAll(t, NDJSON)))
+}
+
+func TestWriteBinary(t *testing.T) {
+	b := writeAll(t, Binary)
+
+	assert.Len(t, b, binaryHeaderLen(testHeader)+2*binaryFrameLen(testHeader))
+	assert.Equal(t, []byte{
+		'L', 'E', 'Q', 'F', 1,
+		0x80, 0xbb, 0x00, 0x00, // 48000
+		0x00, 0x04, 0x00, 0x00, // 1024
+		0x20, 0x03, 0x00, 0x00, // 800
+		0x03, 0x00, // 3 edges
+	}, b[:19])
+	assert.Equal(t, float32(200), math.Float32frombits(binary.LittleEndian.Uint32(b[27:])))
+
+	f := b[binaryHeaderLen(testHeader)+binaryFrameLen(testHeader):]
+	assert.Equal(t, uint64(800), binary.LittleEndian.Uint64(f))
+	assert.Equal(t, float32(0.125), math.Float32frombits(binary.LittleEndian.Uint32(f[12:])))
+}
+
+func TestWrongValueCount(t *testing.T) {
+	for _, format := range []Format{CSV, NDJSON, Binary} {
+		w, err := NewWriter(io.Discard, format, testHeader)
+		failIfErr(t, err)
+		assert.Error(t, w.WriteFrame(Frame{Values: []float64{1, 2, 3}}), format.String())
+	}
+}
+
+// rampReader produces the sample index as each value
+type rampReader struct {
+	i, len int
+}
+
+func (r *rampReader) ReadMono(p []float64) (n int, err error) {
+	for n = range p {
+		if r.i >= r.len {
+			return n, io.EOF
+		}
+		p[n] = float64(r.i)
+		r.i++
+	}
+	return len(p), nil
+}
+
+// captureWriter records frames
+type captureWriter struct {
+	frames []Frame
+}
+
+func (w *captureWriter) WriteFrame(f Frame) error {
+	w.frames = append(w.frames, Frame{Sample: f.Sample, Values: slices.Clone(f.Values)})
+	return nil
+}
+
+func (w *captureWriter) Flush() error {
+	return nil
+}
+
+func samples(frames []Frame) []int64 {
+	s := make([]int64, len(frames))
+	for i, f := range frames {
+		s[i] = f.Sample
+	}
+	return s
+}
+
+func TestExportOverlapping(t *testing.T) {
+	e := eq.New(48_000, 64, 4)
+	w := &captureWriter{}
+	failIfErr(t, Export(&rampReader{len: 200}, &e, 48, w))
+
+	// windows start every 48 samples and must fit entirely
+	assert.Equal(t, []int64{0, 48, 96}, samples(w.frames))
+}
+
+func TestExportSkipping(t *testing.T) {
+	e := eq.New(48_000, 64, 4)
+	w := &captureWriter{}
+	failIfErr(t, Export(&rampReader{len: 300}, &e, 100, w))
+
+	assert.Equal(t, []int64{0, 100, 200}, samples(w.frames))
+}
+
+func TestHopForFrameRate(t *testing.T) {
+	assert.Equal(t, 800, HopForFrameRate(48_000, 60))
+	assert.Equal(t, 735, HopForFrameRate(44_100, 60))
+	assert.Equal(t, 1, HopForFrameRate(10, 1000))
+}
+
+func TestExportSine(t *testing.T) {
+	wv, err := wav.OpenWavFile("../eq/testdata/440sin_1.wav")
+	failIfErr(t, err)
+	defer wv.Close()
+
+	e := eq.New(wv.SampleRate(), 2048, 16)
+	w := &captureWriter{}
+	hop := HopForFrameRate(wv.SampleRate(), 30)
+	failIfErr(t, Export(wv, &e, hop, w))
+
+	assert.Equal(t, (wv.LenSamples()-e.N)/hop+1, len(w.frames))
+	for _, f := range w.frames {
+		peak := slices.Index(f.Values, slices.Max(f.Values))
+		lo, hi := e.OutBins.Bounds(peak)
+		assert.True(t, lo <= 440 && hi > 440)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, format := range []Format{CSV, NDJSON, Binary} {
+		r, err := NewReader(bytes.NewReader(writeAll(t, format)), format)
+		failIfErr(t, err)
+
+		assert.Equal(t, testHeader, r.Header(), format.String())
+		frames, err := ReadAll(r)
+		assert.NoError(t, err, format.String())
+		assert.Equal(t, testFrames, frames, format.String())
+	}
+}
+
+func TestRoundTripSilenceInDB(t *testing.T) {
+	silent := []Frame{{Sample: 0, Values: []float64{math.Inf(-1), -6}}}
+	for _, format := range []Format{CSV, NDJSON, Binary} {
+		var buf bytes.Buffer
+		w, err := NewWriter(&buf, format, testHeader)
+		failIfErr(t, err)
+		failIfErr(t, w.WriteFrame(silent[0]))
+		failIfErr(t, w.Flush())
+
+		r, err := NewReader(&buf, format)
+		failIfErr(t, err)
+		frames, err := ReadAll(r)
+		assert.NoError(t, err, format.String())
+		assert.Equal(t, silent, frames, format.String())
+	}
+}
+
+func TestReadWrongFormat(t *testing.T) {
+	_, err := NewReader(bytes.NewReader(writeAll(t, CSV)), Binary)
+	assert.Error(t, err)
+
+	_, err = NewReader(bytes.NewReader(writeAll(t, Binary)), CSV)
+	assert.Error(t, err)
+
+	_, err = NewReader(bytes.NewReader(writeAll(t, CSV)), NDJSON)
+	assert.Error(t, err)
+}
+
+func TestReadTruncatedBinary(t *testing.T) {
+	b := writeAll(t, Binary)
+	r, err := NewReader(bytes.NewReader(b[:len(b)-3]), Binary)
+	failIfErr(t, err)
+
+	frames, err := ReadAll(r)
+	assert.Len(t, frames, 1)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
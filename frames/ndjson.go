@@ -0,0 +1,122 @@
+package frames
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// The NDJSON format has the header object on the first line and one object
+// per frame after it:
+//
+//	{"sample_rate":48000,"n":1024,"hop":800,"bins":[50,100,200]}
+//	{"t":0.016667,"sample":800,"values":[0.25,0.5]}
+//
+// JSON has no infinities, so values which aren't finite are written as null,
+// which reads back as -Inf: the dB of a silent band.
+
+type jsonHeader struct {
+	SampleRate int       `json:"sample_rate"`
+	N          int       `json:"n"`
+	Hop        int       `json:"hop"`
+	Bins       []float64 `json:"bins"`
+}
+
+type jsonFrame struct {
+	T      float64    `json:"t"`
+	Sample int64      `json:"sample"`
+	Values jsonValues `json:"values"`
+}
+
+type jsonValues []float64
+
+func (v jsonValues) MarshalJSON() ([]byte, error) {
+	b := []byte{'['}
+	for i, f := range v {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			b = append(b, "null"...)
+		} else {
+			b = strconv.AppendFloat(b, f, 'g', -1, 64)
+		}
+	}
+	return append(b, ']'), nil
+}
+
+func (v *jsonValues) UnmarshalJSON(b []byte) error {
+	var ps []*float64
+	if err := json.Unmarshal(b, &ps); err != nil {
+		return err
+	}
+	*v = make(jsonValues, len(ps))
+	for i, p := range ps {
+		if p == nil {
+			(*v)[i] = math.Inf(-1)
+		} else {
+			(*v)[i] = *p
+		}
+	}
+	return nil
+}
+
+type ndjsonWriter struct {
+	enc *json.Encoder
+	h   Header
+}
+
+func newNDJSONWriter(w io.Writer, h Header) (*ndjsonWriter, error) {
+	enc := json.NewEncoder(w)
+	err := enc.Encode(jsonHeader{SampleRate: h.SampleRate, N: h.N, Hop: h.Hop, Bins: h.Bins})
+	if err != nil {
+		return nil, err
+	}
+	return &ndjsonWriter{enc: enc, h: h}, nil
+}
+
+func (nw *ndjsonWriter) WriteFrame(f Frame) error {
+	if len(f.Values) != nw.h.Bins.Len() {
+		return fmt.Errorf("frames: expected %d values but got %d", nw.h.Bins.Len(), len(f.Values))
+	}
+	return nw.enc.Encode(jsonFrame{T: nw.h.Time(f.Sample).Seconds(), Sample: f.Sample, Values: f.Values})
+}
+
+func (nw *ndjsonWriter) Flush() error {
+	return nil // json.Encoder doesn't buffer
+}
+
+type ndjsonReader struct {
+	dec *json.Decoder
+	h   Header
+}
+
+func newNDJSONReader(r io.Reader) (*ndjsonReader, error) {
+	dec := json.NewDecoder(r)
+	var jh jsonHeader
+	if err := dec.Decode(&jh); err != nil {
+		return nil, fmt.Errorf("frames: reading json header: %w", err)
+	}
+	h := Header{SampleRate: jh.SampleRate, N: jh.N, Hop: jh.Hop, Bins: jh.Bins}
+	if err := h.validate(); err != nil {
+		return nil, err
+	}
+	return &ndjsonReader{dec: dec, h: h}, nil
+}
+
+func (nr *ndjsonReader) Header() Header {
+	return nr.h
+}
+
+func (nr *ndjsonReader) ReadFrame() (Frame, error) {
+	var jf jsonFrame
+	if err := nr.dec.Decode(&jf); err != nil {
+		return Frame{}, err
+	}
+	if len(jf.Values) != nr.h.Bins.Len() {
+		return Frame{}, fmt.Errorf("frames: expected %d values but got %d", nr.h.Bins.Len(), len(jf.Values))
+	}
+	return Frame{Sample: jf.Sample, Values: jf.Values}, nil
+}
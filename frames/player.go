@@ -0,0 +1,195 @@
+package frames
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Renderer receives frames from a [Player]. It matches the app's Display.
+type Renderer interface {
+	Render(values []float64) error
+}
+
+// Player renders recorded frames to a [Renderer] at their original timing,
+// scaled by the playback speed. It is safe to control from other goroutines
+// while [Player.Play] is running.
+type Player struct {
+	h      Header
+	frames []Frame
+	d      Renderer
+
+	mu     sync.Mutex
+	speed  float64
+	paused bool
+	pos    time.Duration // media position at `at`
+	at     time.Time
+	next   int           // index of the next frame to render
+	wake   chan struct{} // signalled when controls change
+
+	now func() time.Time
+}
+
+// NewPlayer creates a player positioned at the start of frames, which must
+// be sorted by sample.
+func NewPlayer(h Header, frames []Frame, d Renderer) *Player {
+	return &Player{
+		h:      h,
+		frames: frames,
+		d:      d,
+		speed:  1,
+		wake:   make(chan struct{}, 1),
+		now:    time.Now,
+	}
+}
+
+// Duration is the timestamp of the last frame.
+func (p *Player) Duration() time.Duration {
+	if len(p.frames) == 0 {
+		return 0
+	}
+	return p.h.Time(p.frames[len(p.frames)-1].Sample)
+}
+
+// Position is the current media position.
+func (p *Player) Position() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.position()
+}
+
+func (p *Player) position() time.Duration {
+	if p.paused || p.at.IsZero() {
+		return p.pos
+	}
+	return p.pos + time.Duration(float64(p.now().Sub(p.at))*p.speed)
+}
+
+// rebase records the current position so controls take effect from now
+func (p *Player) rebase() {
+	p.pos = p.position()
+	if !p.at.IsZero() {
+		p.at = p.now()
+	}
+}
+
+func (p *Player) signal() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (p *Player) Pause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rebase()
+	p.paused = true
+	p.signal()
+}
+
+func (p *Player) Resume() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rebase()
+	p.paused = false
+	p.signal()
+}
+
+func (p *Player) Paused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.paused
+}
+
+// Seek moves to t. The frame at or just before t is shown immediately.
+func (p *Player) Seek(t time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rebase()
+	p.pos = min(max(t, 0), p.Duration())
+	s := p.h.Sample(p.pos)
+	// first frame after the position, then step back to show the current one
+	p.next = sort.Search(len(p.frames), func(i int) bool { return p.frames[i].Sample > s })
+	p.next = max(p.next-1, 0)
+	p.signal()
+}
+
+// SetSpeed sets the playback rate, where 1 is real time.
+func (p *Player) SetSpeed(speed float64) {
+	if speed <= 0 {
+		panic("frames: speed must be positive")
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rebase()
+	p.speed = speed
+	p.signal()
+}
+
+func (p *Player) Speed() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.speed
+}
+
+// Play renders frames until the end of the recording or until ctx is done.
+func (p *Player) Play(ctx context.Context) error {
+	p.mu.Lock()
+	p.at = p.now()
+	p.mu.Unlock()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		values, wait, done := p.step()
+		if values != nil {
+			if err := p.d.Render(values); err != nil {
+				return err
+			}
+		}
+		if done {
+			return nil
+		}
+		if wait < 0 { // paused, wait for a control
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-p.wake:
+			}
+			continue
+		}
+
+		timer.Reset(wait)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-p.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// step finds the frame due at the current position, if any, and how long to
+// wait until the next one (negative while paused). If playback fell behind,
+// only the most recent due frame is returned so it catches up.
+func (p *Player) step() (values []float64, wait time.Duration, done bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pos := p.position()
+	for p.next < len(p.frames) && p.h.Time(p.frames[p.next].Sample) <= pos {
+		values = p.frames[p.next].Values
+		p.next++
+	}
+	if p.next >= len(p.frames) {
+		return values, 0, true
+	}
+	if p.paused {
+		return values, -1, false
+	}
+	wait = time.Duration(float64(p.h.Time(p.frames[p.next].Sample)-pos) / p.speed)
+	return values, wait, false
+}
@@ -0,0 +1,158 @@
+package frames
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 10 frames, 100ms apart, each holding its index
+func testPlayer() (*Player, *time.Time) {
+	h := Header{SampleRate: 1000, N: 100, Hop: 100, Bins: []float64{0, 1}}
+	frames := make([]Frame, 10)
+	for i := range frames {
+		frames[i] = Frame{Sample: int64(i * 100), Values: []float64{float64(i)}}
+	}
+	clock := time.Unix(0, 0)
+	p := NewPlayer(h, frames, nil)
+	p.now = func() time.Time { return clock }
+	p.at = clock
+	return p, &clock
+}
+
+func stepValue(p *Player) (v float64, wait time.Duration, ok bool) {
+	values, wait, _ := p.step()
+	if values == nil {
+		return 0, wait, false
+	}
+	return values[0], wait, true
+}
+
+func TestPlayerTiming(t *testing.T) {
+	p, clock := testPlayer()
+
+	v, wait, ok := stepValue(p)
+	assert.True(t, ok)
+	assert.Equal(t, 0.0, v)
+	assert.Equal(t, 100*time.Millisecond, wait)
+
+	*clock = clock.Add(50 * time.Millisecond)
+	_, wait, ok = stepValue(p)
+	assert.False(t, ok, "nothing due yet")
+	assert.Equal(t, 50*time.Millisecond, wait)
+
+	*clock = clock.Add(50 * time.Millisecond)
+	v, _, ok = stepValue(p)
+	assert.True(t, ok)
+	assert.Equal(t, 1.0, v)
+}
+
+func TestPlayerCatchesUp(t *testing.T) {
+	p, clock := testPlayer()
+	stepValue(p)
+
+	*clock = clock.Add(350 * time.Millisecond)
+	v, wait, _ := stepValue(p)
+	assert.Equal(t, 3.0, v, "skips to the latest due frame")
+	assert.Equal(t, 50*time.Millisecond, wait)
+}
+
+func TestPlayerSpeed(t *testing.T) {
+	p, clock := testPlayer()
+	stepValue(p)
+
+	p.SetSpeed(2)
+	_, wait, _ := stepValue(p)
+	assert.Equal(t, 50*time.Millisecond, wait)
+
+	*clock = clock.Add(100 * time.Millisecond)
+	v, _, _ := stepValue(p)
+	assert.Equal(t, 2.0, v)
+	assert.Equal(t, 200*time.Millisecond, p.Position())
+	assert.Equal(t, 2.0, p.Speed())
+}
+
+func TestPlayerPause(t *testing.T) {
+	p, clock := testPlayer()
+	stepValue(p)
+
+	*clock = clock.Add(150 * time.Millisecond)
+	p.Pause()
+	assert.True(t, p.Paused())
+	v, wait, _ := stepValue(p)
+	assert.Equal(t, 1.0, v)
+	assert.Negative(t, wait)
+
+	*clock = clock.Add(10 * time.Second)
+	assert.Equal(t, 150*time.Millisecond, p.Position())
+	_, _, ok := stepValue(p)
+	assert.False(t, ok)
+
+	p.Resume()
+	*clock = clock.Add(50 * time.Millisecond)
+	v, _, _ = stepValue(p)
+	assert.Equal(t, 2.0, v)
+}
+
+func TestPlayerSeek(t *testing.T) {
+	p, clock := testPlayer()
+	stepValue(p)
+
+	p.Seek(720 * time.Millisecond)
+	v, wait, _ := stepValue(p)
+	assert.Equal(t, 7.0, v, "shows the frame at the new position")
+	assert.Equal(t, 80*time.Millisecond, wait)
+
+	p.Seek(200 * time.Millisecond)
+	v, _, _ = stepValue(p)
+	assert.Equal(t, 2.0, v, "seek backwards")
+
+	*clock = clock.Add(100 * time.Millisecond)
+	v, _, _ = stepValue(p)
+	assert.Equal(t, 3.0, v)
+
+	p.Seek(-time.Second)
+	assert.Equal(t, time.Duration(0), p.Position())
+	p.Seek(time.Hour)
+	assert.Equal(t, p.Duration(), p.Position())
+}
+
+// recorder collects rendered values
+type recorder struct {
+	values []float64
+}
+
+func (r *recorder) Render(values []float64) error {
+	r.values = append(r.values, values[0])
+	return nil
+}
+
+func TestPlay(t *testing.T) {
+	h := Header{SampleRate: 1000, N: 10, Hop: 10, Bins: []float64{0, 1}}
+	frames := make([]Frame, 5)
+	for i := range frames {
+		frames[i] = Frame{Sample: int64(i * 10), Values: []float64{float64(i)}}
+	}
+	r := &recorder{}
+	p := NewPlayer(h, frames, r)
+
+	start := time.Now()
+	assert.NoError(t, p.Play(context.Background()))
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+	assert.NotEmpty(t, r.values)
+	assert.Equal(t, 4.0, r.values[len(r.values)-1])
+}
+
+func TestPlayCancel(t *testing.T) {
+	p, _ := testPlayer()
+	p.now = time.Now
+	p.at = time.Time{}
+	p.d = &recorder{}
+	p.Pause()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, p.Play(ctx), context.DeadlineExceeded)
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// FrameTime places an analysis frame in the audio stream.
+type FrameTime struct {
+	// Sample is the position of the start of the frame's window
+	Sample int64
+	// At is when the middle of the window was handed to the audio output
+	At time.Time
+}
+
+// TimedDisplay is a [Display] which wants to know where each frame is in
+// the stream. [EQStreamWrapper] calls RenderAt instead of Render for these.
+type TimedDisplay interface {
+	Display
+	RenderAt(t FrameTime, values []float64) error
+}
+
+// speakerLatency estimates how long after the speaker pulls audio it is
+// heard: each chunk waits behind one buffer of bufferSize samples. Latency
+// in the device itself isn't known, so set a delay explicitly to cover it.
+func speakerLatency(sampleRate, bufferSize int) time.Duration {
+	return samplesToDuration(int64(bufferSize), sampleRate)
+}
+
+// DelayedDisplay holds each frame back until delay after its audio was
+// handed to the output, so the display matches what is heard instead of
+// running ahead by the output's buffering. Frames are passed on from its own
+// goroutine, so d should not block. If it falls behind, frames which are
+// overdue are skipped in favor of the latest one.
+type DelayedDisplay struct {
+	// OnDrop, if set, is called with the number of overdue frames each
+	// time some are skipped. Set it before the first Render.
+	OnDrop func(d Display, n uint64)
+
+	d     Display
+	delay time.Duration
+
+	mu      sync.Mutex
+	pending []timedFrame
+	closed  bool
+	wake    chan struct{}
+
+	skipped atomic.Uint64
+	err     atomic.Pointer[error]
+	done    chan struct{}
+}
+
+type timedFrame struct {
+	due    time.Time
+	values []float64
+}
+
+var _ TimedDisplay = (*DelayedDisplay)(nil)
+
+func NewDelayedDisplay(d Display, delay time.Duration) *DelayedDisplay {
+	dd := &DelayedDisplay{d: d, delay: delay, wake: make(chan struct{}, 1), done: make(chan struct{})}
+	go dd.run()
+	return dd
+}
+
+func (dd *DelayedDisplay) run() {
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	for {
+		dd.mu.Lock()
+		if len(dd.pending) == 0 {
+			closed := dd.closed
+			dd.mu.Unlock()
+			if closed {
+				if c, ok := dd.d.(interface{ Close() }); ok {
+					c.Close()
+				}
+				if w, ok := dd.d.(waiter); ok {
+					<-w.Done()
+				}
+				close(dd.done)
+				return
+			}
+			<-dd.wake
+			continue
+		}
+		next := dd.pending[0].due
+		dd.mu.Unlock()
+
+		if wait := time.Until(next); wait > 0 {
+			timer.Reset(wait)
+			<-timer.C
+		}
+
+		// skip ahead to the latest frame which is due
+		dd.mu.Lock()
+		now := time.Now()
+		i := 0
+		for i+1 < len(dd.pending) && !dd.pending[i+1].due.After(now) {
+			i++
+		}
+		f := dd.pending[i]
+		dd.pending = dd.pending[i+1:]
+		dd.mu.Unlock()
+		dd.skipped.Add(uint64(i))
+		if i > 0 && dd.OnDrop != nil {
+			dd.OnDrop(dd.d, uint64(i))
+		}
+
+		if err := dd.d.Render(f.values); err != nil {
+			dd.err.CompareAndSwap(nil, &err)
+		}
+	}
+}
+
+// Render delays a frame from now, for callers which don't know where it is
+// in the stream.
+func (dd *DelayedDisplay) Render(values []float64) error {
+	return dd.RenderAt(FrameTime{At: time.Now()}, values)
+}
+
+// RenderAt queues a copy of values to be rendered at t.At plus the delay.
+// If the display failed, the error is returned.
+func (dd *DelayedDisplay) RenderAt(t FrameTime, values []float64) error {
+	if err := dd.err.Load(); err != nil {
+		return *err
+	}
+	dd.mu.Lock()
+	defer dd.mu.Unlock()
+	if dd.closed {
+		return nil
+	}
+	dd.pending = append(dd.pending, timedFrame{
+		due:    t.At.Add(dd.delay),
+		values: append([]float64(nil), values...),
+	})
+	select {
+	case dd.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Skipped is the number of frames which were overdue and never rendered.
+func (dd *DelayedDisplay) Skipped() uint64 {
+	return dd.skipped.Load()
+}
+
+// Close stops accepting frames. Frames already queued are still rendered
+// on time, since their audio is still playing, then d is closed.
+func (dd *DelayedDisplay) Close() {
+	dd.mu.Lock()
+	defer dd.mu.Unlock()
+	if !dd.closed {
+		dd.closed = true
+		select {
+		case dd.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Done is closed once the queued frames have been rendered and d has
+// finished closing.
+func (dd *DelayedDisplay) Done() <-chan struct{} {
+	return dd.done
+}
+
+// Err reports the errors collected by d, or else the render error, if any.
+func (dd *DelayedDisplay) Err() error {
+	if e, ok := dd.d.(interface{ Err() error }); ok {
+		return e.Err()
+	}
+	if err := dd.err.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
+
+// Stats reports the counters kept by d, if any.
+func (dd *DelayedDisplay) Stats() []DisplayStats {
+	if s, ok := dd.d.(interface{ Stats() []DisplayStats }); ok {
+		return s.Stats()
+	}
+	return nil
+}
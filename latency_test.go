@@ -0,0 +1,106 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rabidaudio/led-eq/eq"
+	"github.com/stretchr/testify/assert"
+)
+
+// timedRecorder is a recordingDisplay which also records frame times
+type timedRecorder struct {
+	recordingDisplay
+	times []FrameTime
+}
+
+func (d *timedRecorder) RenderAt(t FrameTime, values []float64) error {
+	d.times = append(d.times, t)
+	return d.Render(values)
+}
+
+func TestFrameTimes(t *testing.T) {
+	const chunk = 300
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	e := eq.New(48_000, 1024, 8)
+	d := &timedRecorder{}
+	wrap := EQStreamWrapper{Streamer: sine(440, 48_000, 48_000), eq: &e, d: d,
+		now: func() time.Time { return base }}
+	assert.NoError(t, Pace(&wrap, 48_000, chunk, AsFastAsPossible))
+
+	assert.Len(t, d.times, 48_000/1024)
+	for k, ft := range d.times {
+		assert.Equal(t, int64(k*1024), ft.Sample)
+		// the middle of the window, relative to the chunk which completed it
+		chunkStart := ((k+1)*1024 - 1) / chunk * chunk
+		expected := base.Add(samplesToDuration(int64(k*1024+512-chunkStart), 48_000))
+		assert.Equal(t, expected, ft.At, "frame %d", k)
+	}
+}
+
+func TestDelayedDisplayWaits(t *testing.T) {
+	d := &syncRecorder{}
+	dd := NewDelayedDisplay(d, 50*time.Millisecond)
+	defer dd.Close()
+
+	start := time.Now()
+	assert.NoError(t, dd.RenderAt(FrameTime{At: start}, []float64{1}))
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, 0, d.count())
+	assert.Eventually(t, func() bool { return d.count() == 1 }, time.Second, time.Millisecond)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestDelayedDisplaySkipsOverdue(t *testing.T) {
+	d := &syncRecorder{}
+	dd := NewDelayedDisplay(d, 0)
+	defer dd.Close()
+
+	// all come due at once, so only the latest is worth rendering
+	at := time.Now().Add(30 * time.Millisecond)
+	for i := range 5 {
+		assert.NoError(t, dd.RenderAt(FrameTime{At: at}, []float64{float64(i)}))
+	}
+	assert.Eventually(t, func() bool { return d.count() == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, uint64(4), dd.Skipped())
+	d.mu.Lock()
+	assert.Equal(t, []float64{4}, d.frames[0])
+	d.mu.Unlock()
+}
+
+func TestDelayedDisplayClose(t *testing.T) {
+	d := &syncRecorder{}
+	dd := NewDelayedDisplay(d, 20*time.Millisecond)
+	assert.NoError(t, dd.Render([]float64{1}))
+	dd.Close()
+	dd.Close()
+	assert.NoError(t, dd.Render([]float64{2}), "ignored once closed")
+
+	// the queued frame still plays out
+	assert.Eventually(t, func() bool { return d.count() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, 1, d.count())
+}
+
+func TestDelayedDisplayError(t *testing.T) {
+	dd := NewDelayedDisplay(failingDisplay{}, time.Millisecond)
+	defer dd.Close()
+
+	assert.NoError(t, dd.Render([]float64{1}))
+	assert.Eventually(t, func() bool {
+		return dd.Render([]float64{1}) != nil
+	}, time.Second, time.Millisecond)
+	assert.ErrorIs(t, dd.Err(), errFailed)
+}
+
+func TestDelayedDisplayStats(t *testing.T) {
+	d := &syncRecorder{}
+	dd := NewDelayedDisplay(NewMultiDisplay(DropOnError, 1, d), time.Millisecond)
+	assert.NoError(t, dd.Render([]float64{1}))
+	dd.Close()
+	<-dd.Done()
+	if stats := dd.Stats(); assert.Len(t, stats, 1) {
+		assert.Equal(t, uint64(1), stats[0].Rendered)
+	}
+	assert.Nil(t, NewDelayedDisplay(d, 0).Stats())
+}
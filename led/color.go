@@ -0,0 +1,297 @@
+package led
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// HSV creates a color from hue in degrees, and saturation and value from 0
+// to 1.
+func HSV(h, s, v float64) Color {
+	h = math.Mod(h, 360)
+	if h < 0 {
+		h += 360
+	}
+	s, v = clamp01(s), clamp01(v)
+	c := v * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g, b = c, x, 0
+	case h < 120:
+		r, g, b = x, c, 0
+	case h < 180:
+		r, g, b = 0, c, x
+	case h < 240:
+		r, g, b = 0, x, c
+	case h < 300:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	m := v - c
+	return Color{R: to8(r + m), G: to8(g + m), B: to8(b + m)}
+}
+
+// HSV is the inverse of [HSV], ignoring W.
+func (c Color) HSV() (h, s, v float64) {
+	r, g, b := float64(c.R)/255, float64(c.G)/255, float64(c.B)/255
+	hi, lo := max(r, g, b), min(r, g, b)
+	d := hi - lo
+	switch {
+	case d == 0:
+		h = 0
+	case hi == r:
+		h = 60 * math.Mod((g-b)/d, 6)
+	case hi == g:
+		h = 60 * ((b-r)/d + 2)
+	default:
+		h = 60 * ((r-g)/d + 4)
+	}
+	if h < 0 {
+		h += 360
+	}
+	if hi > 0 {
+		s = d / hi
+	}
+	return h, s, hi
+}
+
+// ParseColor parses a hex color such as "#ff8000" or "ff8000".
+func ParseColor(s string) (Color, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) != 6 {
+		return Color{}, fmt.Errorf("invalid color %q", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return Color{}, fmt.Errorf("invalid color %q", s)
+	}
+	return Color{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v)}, nil
+}
+
+func clamp01(f float64) float64 {
+	return min(max(f, 0), 1)
+}
+
+func to8(f float64) uint8 {
+	return uint8(math.Round(clamp01(f) * 255))
+}
+
+// oklab is a color in the OKLab perceptual color space, where straight
+// lines between colors look like even transitions.
+type oklab struct {
+	l, a, b float64
+}
+
+func linear(v uint8) float64 {
+	f := float64(v) / 255
+	if f <= 0.04045 {
+		return f / 12.92
+	}
+	return math.Pow((f+0.055)/1.055, 2.4)
+}
+
+func srgb(f float64) uint8 {
+	f = clamp01(f)
+	if f <= 0.0031308 {
+		return to8(f * 12.92)
+	}
+	return to8(1.055*math.Pow(f, 1/2.4) - 0.055)
+}
+
+func toOKLab(c Color) oklab {
+	r, g, b := linear(c.R), linear(c.G), linear(c.B)
+	l := math.Cbrt(0.4122214708*r + 0.5363325363*g + 0.0514459929*b)
+	m := math.Cbrt(0.2119034982*r + 0.6806995451*g + 0.1073969566*b)
+	s := math.Cbrt(0.0883024619*r + 0.2817188376*g + 0.6299787005*b)
+	return oklab{
+		l: 0.2104542553*l + 0.7936177850*m - 0.0040720468*s,
+		a: 1.9779984951*l - 2.4285922050*m + 0.4505937099*s,
+		b: 0.0259040371*l + 0.7827717662*m - 0.8086757660*s,
+	}
+}
+
+func (c oklab) color() Color {
+	l := c.l + 0.3963377774*c.a + 0.2158037573*c.b
+	m := c.l - 0.1055613458*c.a - 0.0638541728*c.b
+	s := c.l - 0.0894841775*c.a - 1.2914855480*c.b
+	l, m, s = l*l*l, m*m*m, s*s*s
+	return Color{
+		R: srgb(4.0767416621*l - 3.3077115913*m + 0.2309699292*s),
+		G: srgb(-1.2684380046*l + 2.6097574011*m - 0.3413193965*s),
+		B: srgb(-0.0041960863*l - 0.7034186147*m + 1.7076147010*s),
+	}
+}
+
+// Stop is a color at a position from 0 to 1 along a [Gradient].
+type Stop struct {
+	Pos   float64
+	Color Color
+}
+
+// Gradient blends between stops, which must be in order of position. The
+// blend is done in OKLab so it doesn't dip in brightness or go muddy
+// between colors, as blending sRGB values directly does. W is blended
+// linearly.
+type Gradient []Stop
+
+// EvenGradient spaces colors evenly from 0 to 1.
+func EvenGradient(colors ...Color) Gradient {
+	g := make(Gradient, len(colors))
+	for i, c := range colors {
+		g[i] = Stop{Color: c}
+		if len(colors) > 1 {
+			g[i].Pos = float64(i) / float64(len(colors)-1)
+		}
+	}
+	return g
+}
+
+// VU is green through yellow to red, like a level meter.
+var VU = EvenGradient(Color{G: 255}, Color{R: 255, G: 255}, Color{R: 255})
+
+// At is the color at t. Before the first stop and after the last, the end
+// colors are used.
+func (g Gradient) At(t float64) Color {
+	switch {
+	case len(g) == 0:
+		return Color{}
+	case t <= g[0].Pos:
+		return g[0].Color
+	case t >= g[len(g)-1].Pos:
+		return g[len(g)-1].Color
+	}
+	i := 1
+	for g[i].Pos < t {
+		i++
+	}
+	a, b := g[i-1], g[i]
+	if b.Pos == a.Pos {
+		return b.Color
+	}
+	f := (t - a.Pos) / (b.Pos - a.Pos)
+	la, lb := toOKLab(a.Color), toOKLab(b.Color)
+	c := oklab{
+		l: la.l + f*(lb.l-la.l),
+		a: la.a + f*(lb.a-la.a),
+		b: la.b + f*(lb.b-la.b),
+	}.color()
+	c.W = uint8(math.Round(float64(a.Color.W) + f*(float64(b.Color.W)-float64(a.Color.W))))
+	return c
+}
+
+// ByBand colors each bar by its band along the gradient, from the lowest
+// band at 0 to the highest at 1.
+type ByBand struct {
+	Gradient Gradient
+}
+
+func (c ByBand) Color(band, bands int, pos float64) Color {
+	if bands <= 1 {
+		return c.Gradient.At(0)
+	}
+	return c.Gradient.At(float64(band) / float64(bands-1))
+}
+
+// ByLevel colors pixels by how far along the bar they are, so a bar only
+// reaches the end of the gradient at full level, as on a VU meter.
+type ByLevel struct {
+	Gradient Gradient
+}
+
+func (c ByLevel) Color(band, bands int, pos float64) Color {
+	return c.Gradient.At(pos)
+}
+
+// Rainbow spreads the bands around the hue wheel, from hue From to To in
+// degrees, at full saturation and value.
+type Rainbow struct {
+	From, To float64
+}
+
+func (c Rainbow) Color(band, bands int, pos float64) Color {
+	if bands <= 1 {
+		return HSV(c.From, 1, 1)
+	}
+	return HSV(c.From+(c.To-c.From)*float64(band)/float64(bands-1), 1, 1)
+}
+
+// Gamma is a lookup table correcting for the nonlinear brightness of LEDs,
+// so that values in between look evenly spaced.
+type Gamma [256]uint8
+
+// NewGamma builds a table for exponent g. 2.2 to 2.8 suits most LEDs, and 1
+// leaves values unchanged.
+func NewGamma(g float64) *Gamma {
+	var t Gamma
+	for i := range t {
+		t[i] = uint8(math.Round(math.Pow(float64(i)/255, g) * 255))
+	}
+	return &t
+}
+
+func (t *Gamma) Apply(c Color) Color {
+	return Color{t[c.R], t[c.G], t[c.B], t[c.W]}
+}
+
+// LoadPalette reads a GIMP palette (.gpl) file as an evenly spaced
+// gradient, in the order the colors are listed.
+func LoadPalette(path string) (Gradient, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadPalette(f)
+}
+
+// ReadPalette parses a GIMP palette:
+//
+//	GIMP Palette
+//	Name: Fire
+//	# comment
+//	  0   0   0 black
+//	255 128   0 orange
+func ReadPalette(r io.Reader) (Gradient, error) {
+	sc := bufio.NewScanner(r)
+	if !sc.Scan() || strings.TrimSpace(sc.Text()) != "GIMP Palette" {
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("palette: missing GIMP Palette header")
+	}
+	var colors []Color
+	for line := 2; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") || strings.Contains(strings.SplitN(text, " ", 2)[0], ":") {
+			continue // comments and header fields such as Name: and Columns:
+		}
+		fields := strings.Fields(text)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("palette: line %d: expected r g b", line)
+		}
+		var rgb [3]uint8
+		for i := range rgb {
+			v, err := strconv.ParseUint(fields[i], 10, 8)
+			if err != nil {
+				return nil, fmt.Errorf("palette: line %d: invalid value %q", line, fields[i])
+			}
+			rgb[i] = uint8(v)
+		}
+		colors = append(colors, Color{R: rgb[0], G: rgb[1], B: rgb[2]})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(colors) == 0 {
+		return nil, fmt.Errorf("palette: no colors")
+	}
+	return EvenGradient(colors...), nil
+}
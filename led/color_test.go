@@ -0,0 +1,90 @@
+package led
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHSV(t *testing.T) {
+	assert.Equal(t, Color{R: 255}, HSV(0, 1, 1))
+	assert.Equal(t, Color{G: 255}, HSV(120, 1, 1))
+	assert.Equal(t, Color{R: 255, B: 255}, HSV(-60, 1, 1))
+	assert.Equal(t, Color{R: 102, G: 170, B: 204}, HSV(200, 0.5, 0.8))
+
+	h, s, v := Color{R: 102, G: 170, B: 204}.HSV()
+	assert.InDelta(t, 200, h, 0.5)
+	assert.InDelta(t, 0.5, s, 0.01)
+	assert.InDelta(t, 0.8, v, 0.01)
+}
+
+func TestParseColor(t *testing.T) {
+	c, err := ParseColor("#ff8001")
+	assert.NoError(t, err)
+	assert.Equal(t, Color{R: 255, G: 128, B: 1}, c)
+	_, err = ParseColor("red")
+	assert.Error(t, err)
+}
+
+func TestGradient(t *testing.T) {
+	assert.Equal(t, Color{G: 255}, VU.At(-1))
+	assert.Equal(t, Color{R: 176, G: 255}, VU.At(0.25))
+	assert.Equal(t, Color{R: 255, G: 255}, VU.At(0.5))
+	assert.Equal(t, Color{R: 255, G: 160}, VU.At(0.75))
+	assert.Equal(t, Color{R: 255}, VU.At(2))
+
+	// perceptual: halfway from black to white is a mid grey, not 128, and
+	// red to blue stays purple instead of going dark
+	assert.Equal(t, Color{R: 99, G: 99, B: 99}, EvenGradient(Color{}, Color{R: 255, G: 255, B: 255}).At(0.5))
+	assert.Equal(t, Color{R: 140, G: 83, B: 162}, EvenGradient(Color{R: 255}, Color{B: 255}).At(0.5))
+
+	g := Gradient{{Pos: 0, Color: Color{W: 0}}, {Pos: 0.5, Color: Color{W: 100}}, {Pos: 1, Color: Color{W: 200}}}
+	assert.Equal(t, Color{W: 150}, g.At(0.75))
+}
+
+func TestColorers(t *testing.T) {
+	assert.Equal(t, Color{R: 255, G: 255}, ByBand{Gradient: VU}.Color(1, 3, 0))
+	assert.Equal(t, Color{R: 255}, ByLevel{Gradient: VU}.Color(0, 3, 1))
+	assert.Equal(t, Color{G: 255}, Rainbow{From: 0, To: 240}.Color(1, 3, 0.5))
+}
+
+func TestGamma(t *testing.T) {
+	g := NewGamma(2.2)
+	assert.Equal(t, []uint8{0, 0, 12, 56, 255}, []uint8{g[0], g[1], g[64], g[128], g[255]})
+	assert.Equal(t, Color{R: 56, G: 12, B: 255, W: 0}, g.Apply(Color{R: 128, G: 64, B: 255, W: 1}))
+	linear := NewGamma(1) // identity
+	for i := range linear {
+		assert.Equal(t, uint8(i), linear[i])
+	}
+}
+
+func TestMapperBytes(t *testing.T) {
+	m := Mapper{
+		Strip:      Strip{Pixels: 4, Order: GRB},
+		Layout:     Horizontal{Count: 4},
+		Colors:     ByLevel{Gradient: VU},
+		Gamma:      NewGamma(2.2),
+		Brightness: 0.5,
+	}
+	out := m.Strip.AppendFrame(nil, m.Frame([]float64{1}))
+	assert.Equal(t, []byte{
+		128, 0, 0, // green
+		128, 79, 0, // yellow-green
+		69, 128, 0, // orange
+		0, 128, 0, // red
+	}, out)
+}
+
+func TestReadPalette(t *testing.T) {
+	g, err := LoadPalette("testdata/fire.gpl")
+	assert.NoError(t, err)
+	assert.Equal(t, EvenGradient(Color{}, Color{R: 255}, Color{R: 255, G: 255, B: 255}), g)
+
+	_, err = ReadPalette(strings.NewReader("0 0 0\n"))
+	assert.ErrorContains(t, err, "header")
+	_, err = ReadPalette(strings.NewReader("GIMP Palette\n0 0 300\n"))
+	assert.ErrorContains(t, err, "line 2")
+	_, err = ReadPalette(strings.NewReader("GIMP Palette\n"))
+	assert.ErrorContains(t, err, "no colors")
+}
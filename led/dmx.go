@@ -0,0 +1,299 @@
+package led
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// DMXProtocol is a way of sending DMX universes over UDP.
+type DMXProtocol int
+
+const (
+	// E131 is streaming ACN (sACN), multicast to each universe's group
+	// unless an address is given
+	E131 DMXProtocol = iota
+	// ArtNet sends ArtDmx packets, broadcast unless an address is given
+	ArtNet
+)
+
+func ParseDMXProtocol(s string) (DMXProtocol, error) {
+	switch s {
+	case "e131", "sacn":
+		return E131, nil
+	case "artnet":
+		return ArtNet, nil
+	}
+	return 0, fmt.Errorf("unknown DMX protocol %q", s)
+}
+
+func (p DMXProtocol) String() string {
+	switch p {
+	case E131:
+		return "e131"
+	case ArtNet:
+		return "artnet"
+	}
+	return fmt.Sprintf("DMXProtocol(%d)", int(p))
+}
+
+const (
+	e131Port   = 5568
+	artNetPort = 6454
+
+	// universe limits of each protocol
+	e131MaxUniverse   = 63999
+	artNetMaxUniverse = 32767
+
+	// DefaultPriority is the E1.31 priority receivers assume
+	DefaultPriority = 100
+)
+
+// DMXOptions configures a [DMX] output.
+type DMXOptions struct {
+	// Address is the receiver as host or host:port. Empty sends E1.31 to
+	// the multicast group of each universe and Art-Net to broadcast.
+	Address string
+	// StartUniverse is the first universe. E1.31 universes start at 1.
+	StartUniverse int
+	// StartChannel is where the strip starts in the first universe, from 1.
+	// Channels before it are sent as zero.
+	StartChannel int
+	// UniverseSize is how many channels of each universe to use. Zero uses
+	// as many whole pixels as fit, 170 RGB pixels for example, since most
+	// controllers don't expect pixels to straddle universes. 512 packs the
+	// channels tightly.
+	UniverseSize int
+	// Priority is the E1.31 priority from 0 to 200, [DefaultPriority] if
+	// zero.
+	Priority int
+	// SourceName and CID identify the sender to E1.31 receivers. CID is
+	// random if zero.
+	SourceName string
+	CID        [16]byte
+}
+
+// DMX is a display which sends frames as DMX universes over UDP, with E1.31
+// or Art-Net.
+type DMX struct {
+	protocol DMXProtocol
+	mapper   *Mapper
+	opts     DMXOptions
+
+	conn  net.PacketConn
+	dests []net.Addr // for each universe
+	seq   []byte     // next sequence number for each universe
+
+	data, pkt []byte
+	univ      [][]byte
+}
+
+func NewDMX(p DMXProtocol, m *Mapper, opts DMXOptions) (*DMX, error) {
+	ch := m.Strip.Order.Channels()
+	if opts.UniverseSize == 0 {
+		opts.UniverseSize = 512 - 512%ch
+	}
+	if opts.UniverseSize < ch || opts.UniverseSize > 512 {
+		return nil, fmt.Errorf("universe size must be from %d to 512", ch)
+	}
+	if opts.StartChannel == 0 {
+		opts.StartChannel = 1
+	}
+	if opts.StartChannel < 1 || opts.StartChannel+ch-1 > opts.UniverseSize {
+		return nil, fmt.Errorf("start channel must be from 1 to %d", opts.UniverseSize-ch+1)
+	}
+	if opts.Priority == 0 {
+		opts.Priority = DefaultPriority
+	}
+	if opts.Priority < 0 || opts.Priority > 200 {
+		return nil, fmt.Errorf("priority must be from 0 to 200")
+	}
+	if opts.SourceName == "" {
+		opts.SourceName = "led-eq"
+	}
+	if opts.CID == [16]byte{} {
+		rand.Read(opts.CID[:])
+		opts.CID[6] = opts.CID[6]&0x0f | 0x40 // version 4 UUID
+		opts.CID[8] = opts.CID[8]&0x3f | 0x80
+	}
+
+	d := &DMX{protocol: p, mapper: m, opts: opts}
+	d.univ = splitUniverses(d.univ, make([]byte, m.Strip.FrameSize()), ch, opts.UniverseSize, opts.StartChannel)
+	first, last := opts.StartUniverse, opts.StartUniverse+len(d.univ)-1
+	switch p {
+	case E131:
+		if first < 1 || last > e131MaxUniverse {
+			return nil, fmt.Errorf("e131 universes %d to %d are outside 1 to %d", first, last, e131MaxUniverse)
+		}
+	case ArtNet:
+		if first < 0 || last > artNetMaxUniverse {
+			return nil, fmt.Errorf("artnet universes %d to %d are outside 0 to %d", first, last, artNetMaxUniverse)
+		}
+	}
+
+	for u := first; u <= last; u++ {
+		addr, err := d.dest(u)
+		if err != nil {
+			return nil, err
+		}
+		d.dests = append(d.dests, addr)
+	}
+	d.seq = make([]byte, len(d.dests))
+	if p == ArtNet {
+		for i := range d.seq {
+			d.seq[i] = 1 // 0 disables sequencing
+		}
+	}
+	conn, err := net.ListenPacket("udp4", ":0")
+	if err != nil {
+		return nil, err
+	}
+	d.conn = conn
+	return d, nil
+}
+
+// dest resolves where universe u is sent.
+func (d *DMX) dest(u int) (net.Addr, error) {
+	port := e131Port
+	if d.protocol == ArtNet {
+		port = artNetPort
+	}
+	host := d.opts.Address
+	switch {
+	case host == "" && d.protocol == E131:
+		host = fmt.Sprintf("239.255.%d.%d", u>>8, u&0xff)
+	case host == "":
+		host = "255.255.255.255"
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, strconv.Itoa(port))
+	}
+	return net.ResolveUDPAddr("udp4", host)
+}
+
+// splitUniverses lays data out over universes of size channels, starting at
+// channel start of the first. Each universe's data starts at channel 1. If
+// size is a whole number of pixels, pixels are kept within a universe.
+func splitUniverses(univ [][]byte, data []byte, pixelSize, size, start int) [][]byte {
+	univ = univ[:0]
+	offset := start - 1
+	for i := 0; len(data) > 0; i++ {
+		room := size - offset
+		if size%pixelSize == 0 {
+			room -= room % pixelSize
+		}
+		n := min(room, len(data))
+		var u []byte
+		if i < cap(univ) {
+			u = univ[:i+1][i] // reuse the buffer from the last frame
+		}
+		u = append(u[:0], make([]byte, offset)...)
+		u = append(u, data[:n]...)
+		univ = append(univ, u)
+		data = data[n:]
+		offset = 0
+	}
+	return univ
+}
+
+func (d *DMX) Render(values []float64) error {
+	d.data = d.mapper.Strip.AppendFrame(d.data[:0], d.mapper.Frame(values))
+	return d.send(0)
+}
+
+// send writes every universe with the given E1.31 options.
+func (d *DMX) send(options byte) error {
+	d.univ = splitUniverses(d.univ, d.data, d.mapper.Strip.Order.Channels(), d.opts.UniverseSize, d.opts.StartChannel)
+	for i, data := range d.univ {
+		u := d.opts.StartUniverse + i
+		switch d.protocol {
+		case E131:
+			d.pkt = d.appendE131(d.pkt[:0], u, d.seq[i], options, data)
+			d.seq[i]++
+		case ArtNet:
+			d.pkt = appendArtDmx(d.pkt[:0], u, d.seq[i], data)
+			d.seq[i] = d.seq[i]%255 + 1
+		}
+		if _, err := d.conn.WriteTo(d.pkt, d.dests[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// E1.31 option bit marking the last packets of a stream
+const e131Terminated = 1 << 6
+
+// appendE131 builds an E1.31 data packet: the root, framing and DMP layers,
+// each starting with its length.
+func (d *DMX) appendE131(dst []byte, universe int, seq, options byte, data []byte) []byte {
+	be := binary.BigEndian
+	n := len(data)
+	flagsLen := func(length int) uint16 {
+		return 0x7000 | uint16(length)
+	}
+
+	// root layer
+	dst = be.AppendUint16(dst, 0x0010) // preamble size
+	dst = be.AppendUint16(dst, 0x0000) // postamble size
+	dst = append(dst, "ASC-E1.17\x00\x00\x00"...)
+	dst = be.AppendUint16(dst, flagsLen(110+n))
+	dst = be.AppendUint32(dst, 0x00000004) // VECTOR_ROOT_E131_DATA
+	dst = append(dst, d.opts.CID[:]...)
+
+	// framing layer
+	dst = be.AppendUint16(dst, flagsLen(88+n))
+	dst = be.AppendUint32(dst, 0x00000002) // VECTOR_E131_DATA_PACKET
+	var name [64]byte
+	copy(name[:63], d.opts.SourceName)
+	dst = append(dst, name[:]...)
+	dst = append(dst, byte(d.opts.Priority))
+	dst = be.AppendUint16(dst, 0) // synchronization address
+	dst = append(dst, seq, options)
+	dst = be.AppendUint16(dst, uint16(universe))
+
+	// DMP layer
+	dst = be.AppendUint16(dst, flagsLen(11+n))
+	dst = append(dst, 0x02, 0xa1)      // VECTOR_DMP_SET_PROPERTY, address and data type
+	dst = be.AppendUint16(dst, 0x0000) // first property address
+	dst = be.AppendUint16(dst, 0x0001) // address increment
+	dst = be.AppendUint16(dst, uint16(n+1))
+	dst = append(dst, 0x00) // DMX start code
+	return append(dst, data...)
+}
+
+// appendArtDmx builds an Art-Net ArtDmx packet. The data length must be
+// even, so odd lengths are padded.
+func appendArtDmx(dst []byte, universe int, seq byte, data []byte) []byte {
+	n := len(data) + len(data)%2
+	dst = append(dst, "Art-Net\x00"...)
+	dst = binary.LittleEndian.AppendUint16(dst, 0x5000) // OpDmx
+	dst = binary.BigEndian.AppendUint16(dst, 14)        // protocol version
+	dst = append(dst, seq, 0, byte(universe), byte(universe>>8)&0x7f)
+	dst = binary.BigEndian.AppendUint16(dst, uint16(n))
+	dst = append(dst, data...)
+	if len(data)%2 == 1 {
+		dst = append(dst, 0)
+	}
+	return dst
+}
+
+// Close tells E1.31 receivers the stream has ended, so they can move on to
+// other sources straight away rather than holding the last frame.
+func (d *DMX) Close() error {
+	var err error
+	if d.protocol == E131 && d.data != nil {
+		// the standard asks for three terminating packets
+		for range 3 {
+			if err = d.send(e131Terminated); err != nil {
+				break
+			}
+		}
+	}
+	if cerr := d.conn.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,160 @@
+package led
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// listenUDP starts a local receiver, returning its address and a function
+// reading the next packet
+func listenUDP(t *testing.T) (string, func() []byte) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn.LocalAddr().String(), func() []byte {
+		buf := make([]byte, 2048)
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return buf[:n]
+	}
+}
+
+func TestSplitUniverses(t *testing.T) {
+	data := make([]byte, 600)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	univ := splitUniverses(nil, data, 3, 510, 1)
+	assert.Len(t, univ, 2)
+	assert.Len(t, univ[0], 510)
+	assert.Equal(t, data[510:], univ[1])
+
+	// starting at channel 5, a pixel can't fit at 506 so moves on
+	univ = splitUniverses(univ, data, 3, 510, 5)
+	assert.Len(t, univ, 2)
+	assert.Len(t, univ[0], 4+504)
+	assert.Equal(t, []byte{0, 0, 0, 0, 0, 1}, univ[0][:6])
+	assert.Equal(t, data[504:], univ[1])
+
+	// packed tightly, pixels straddle
+	univ = splitUniverses(univ, data, 3, 512, 1)
+	assert.Equal(t, [][]byte{data[:512], data[512:]}, univ)
+}
+
+func dmxMapper(pixels int) *Mapper {
+	return &Mapper{
+		Strip:  Strip{Pixels: pixels, Order: RGB},
+		Layout: Horizontal{Count: pixels},
+		Colors: Solid{R: 1, G: 2, B: 3},
+	}
+}
+
+func TestE131(t *testing.T) {
+	addr, next := listenUDP(t)
+	cid := [16]byte{0: 0xaa, 15: 0xbb}
+	d, err := NewDMX(E131, dmxMapper(2), DMXOptions{
+		Address: addr, StartUniverse: 7, StartChannel: 3, Priority: 150, SourceName: "test", CID: cid,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.NoError(t, d.Render([]float64{1}))
+	assert.NoError(t, d.Render([]float64{0}))
+
+	header := func(seq, options byte) []byte {
+		pkt := []byte{
+			0x00, 0x10, 0x00, 0x00,
+			'A', 'S', 'C', '-', 'E', '1', '.', '1', '7', 0, 0, 0,
+			0x70, 0x76, // 110 + 8
+			0, 0, 0, 4,
+		}
+		pkt = append(pkt, cid[:]...)
+		pkt = append(pkt, 0x70, 0x60, 0, 0, 0, 2) // 88 + 8
+		name := make([]byte, 64)
+		copy(name, "test")
+		pkt = append(pkt, name...)
+		pkt = append(pkt, 150, 0, 0, seq, options, 0, 7)
+		return append(pkt, 0x70, 0x13, 0x02, 0xa1, 0, 0, 0, 1, 0, 9, 0)
+	}
+	assert.Equal(t, append(header(0, 0), 0, 0, 1, 2, 3, 1, 2, 3), next())
+	assert.Equal(t, append(header(1, 0), 0, 0, 0, 0, 0, 0, 0, 0), next())
+
+	assert.NoError(t, d.Close())
+	for seq := range byte(3) {
+		assert.Equal(t, append(header(2+seq, 0x40), 0, 0, 0, 0, 0, 0, 0, 0), next(), "stream terminated")
+	}
+}
+
+func TestE131Universes(t *testing.T) {
+	addr, next := listenUDP(t)
+	d, err := NewDMX(E131, dmxMapper(200), DMXOptions{Address: addr, StartUniverse: 1})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer d.Close()
+	assert.NoError(t, d.Render([]float64{1}))
+
+	// 170 pixels then 30
+	first, second := next(), next()
+	assert.Len(t, first, 126+510)
+	assert.Equal(t, []byte{0, 1}, first[113:115], "universe")
+	assert.Len(t, second, 126+90)
+	assert.Equal(t, []byte{0, 2}, second[113:115], "universe")
+	assert.Equal(t, byte(0), second[111], "sequence is per universe")
+}
+
+func TestE131Multicast(t *testing.T) {
+	d, err := NewDMX(E131, dmxMapper(171), DMXOptions{StartUniverse: 0x1234})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer d.Close()
+	assert.Equal(t, "239.255.18.52:5568", d.dests[0].String())
+	assert.Equal(t, "239.255.18.53:5568", d.dests[1].String())
+}
+
+func TestArtNet(t *testing.T) {
+	addr, next := listenUDP(t)
+	d, err := NewDMX(ArtNet, dmxMapper(1), DMXOptions{Address: addr, StartUniverse: 0x123})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer d.Close()
+
+	for range 256 {
+		assert.NoError(t, d.Render([]float64{1}))
+	}
+	header := []byte{'A', 'r', 't', '-', 'N', 'e', 't', 0, 0x00, 0x50, 0, 14}
+	expected := append(header, 1, 0, 0x23, 0x01, 0, 4, 1, 2, 3, 0)
+	assert.Equal(t, expected, next(), "padded to an even length")
+	for range 254 {
+		next()
+	}
+	assert.Equal(t, byte(1), next()[12], "sequence skips 0 when it wraps")
+}
+
+func TestDMXOptions(t *testing.T) {
+	for _, tc := range []struct {
+		p    DMXProtocol
+		opts DMXOptions
+	}{
+		{E131, DMXOptions{StartUniverse: 0}},
+		{E131, DMXOptions{StartUniverse: 63999}}, // needs 2
+		{ArtNet, DMXOptions{StartUniverse: 32767}},
+		{E131, DMXOptions{StartUniverse: 1, StartChannel: 509}},
+		{E131, DMXOptions{StartUniverse: 1, Priority: 201}},
+		{E131, DMXOptions{StartUniverse: 1, UniverseSize: 513}},
+	} {
+		_, err := NewDMX(tc.p, dmxMapper(171), tc.opts)
+		assert.Error(t, err, "%v %+v", tc.p, tc.opts)
+	}
+}
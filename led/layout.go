@@ -0,0 +1,160 @@
+package led
+
+// Layout places bars for each band on the pixels of a strip.
+type Layout interface {
+	// Pixels is the number of pixels the layout covers
+	Pixels() int
+	// Map fills frame from values, coloring lit pixels with c. frame may
+	// be shorter than Pixels if the strip is, and is cleared beforehand.
+	Map(values []float64, c Colorer, frame []Color)
+}
+
+// Colorer picks the color of a lit pixel. band is which of bands it
+// belongs to and pos is how far along the bar it is, from 0 at the base to
+// 1 at the full length.
+type Colorer interface {
+	Color(band, bands int, pos float64) Color
+}
+
+// Solid colors every pixel the same.
+type Solid Color
+
+func (s Solid) Color(band, bands int, pos float64) Color {
+	return Color(s)
+}
+
+// fillBar lights the first level of n pixels, with the last pixel dimmed
+// by how much of it is covered so bars move smoothly. at gives the frame
+// index of each pixel along the bar.
+func fillBar(frame []Color, n int, level float64, band, bands int, c Colorer, at func(i int) int) {
+	lit := min(max(level, 0), 1) * float64(n)
+	for i := 0; i < n && float64(i) < lit; i++ {
+		j := at(i)
+		if j < 0 || j >= len(frame) {
+			continue
+		}
+		pos := 0.0
+		if n > 1 {
+			pos = float64(i) / float64(n-1)
+		}
+		frame[j] = c.Color(band, bands, pos).Scale(lit - float64(i))
+	}
+}
+
+// segment is the range of pixels out of total given to band i of bands.
+func segment(i, bands, total int) (start, n int) {
+	start = i * total / bands
+	return start, (i+1)*total/bands - start
+}
+
+// Horizontal splits a strip into a segment for each band, lit from the
+// start of the segment. With one pixel per band it shows each band as
+// brightness.
+type Horizontal struct {
+	Count int
+}
+
+func (h Horizontal) Pixels() int {
+	return h.Count
+}
+
+func (h Horizontal) Map(values []float64, c Colorer, frame []Color) {
+	for b, v := range values {
+		start, n := segment(b, len(values), h.Count)
+		fillBar(frame, n, v, b, len(values), c, func(i int) int { return start + i })
+	}
+}
+
+// Wiring is how the rows of a matrix are chained together.
+type Wiring int
+
+const (
+	// Progressive rows all run left to right
+	Progressive Wiring = iota
+	// Serpentine rows alternate direction, as when a strip is folded back
+	// and forth
+	Serpentine
+)
+
+// Matrix is a grid of vertical bars, with the bands spread across the
+// columns and bars rising from the bottom. Pixels are wired in rows starting
+// from the top left.
+type Matrix struct {
+	Width, Height int
+	Wiring        Wiring
+}
+
+func (m Matrix) Pixels() int {
+	return m.Width * m.Height
+}
+
+// Index is the pixel index of column x, row y from the top.
+func (m Matrix) Index(x, y int) int {
+	if m.Wiring == Serpentine && y%2 == 1 {
+		x = m.Width - 1 - x
+	}
+	return y*m.Width + x
+}
+
+func (m Matrix) Map(values []float64, c Colorer, frame []Color) {
+	for b, v := range values {
+		start, n := segment(b, len(values), m.Width)
+		for x := start; x < start+n; x++ {
+			fillBar(frame, m.Height, v, b, len(values), c, func(i int) int {
+				return m.Index(x, m.Height-1-i)
+			})
+		}
+	}
+}
+
+// Mirror shows Half twice, reflected about the center of the strip, so
+// the bars grow outwards from the middle.
+type Mirror struct {
+	Half Layout
+}
+
+func (m Mirror) Pixels() int {
+	return 2 * m.Half.Pixels()
+}
+
+func (m Mirror) Map(values []float64, c Colorer, frame []Color) {
+	n := m.Half.Pixels()
+	half := make([]Color, n)
+	m.Half.Map(values, c, half)
+	for i, px := range half {
+		if j := n + i; j < len(frame) {
+			frame[j] = px
+		}
+		if j := n - 1 - i; j < len(frame) {
+			frame[j] = px
+		}
+	}
+}
+
+// Ring splits a circle of pixels into an arc for each band, each lit
+// outwards from the middle of its arc. Offset rotates the first arc
+// clockwise by that many pixels, to line it up with the top of the ring.
+type Ring struct {
+	Count  int
+	Offset int
+}
+
+func (r Ring) Pixels() int {
+	return r.Count
+}
+
+func (r Ring) Map(values []float64, c Colorer, frame []Color) {
+	for b, v := range values {
+		start, n := segment(b, len(values), r.Count)
+		// grow from the middle, alternating sides a pixel at a time
+		mid := start + n/2
+		fillBar(frame, n, v, b, len(values), c, func(i int) int {
+			d := (i + 1) / 2
+			j := mid - d
+			if i%2 == n%2 {
+				j = mid + d
+			}
+			return ((j+r.Offset)%r.Count + r.Count) % r.Count
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package led
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// bandColors colors each pixel by its band, to check where bars land
+type bandColors struct{}
+
+func (bandColors) Color(band, bands int, pos float64) Color {
+	return Color{R: uint8(band + 1), G: uint8(pos * 100)}
+}
+
+func mapLayout(l Layout, values []float64) []Color {
+	frame := make([]Color, l.Pixels())
+	l.Map(values, bandColors{}, frame)
+	return frame
+}
+
+func TestHorizontal(t *testing.T) {
+	frame := mapLayout(Horizontal{Count: 8}, []float64{1, 0.5})
+	assert.Equal(t, []Color{
+		{R: 1, G: 0}, {R: 1, G: 33}, {R: 1, G: 66}, {R: 1, G: 100},
+		{R: 2, G: 0}, {R: 2, G: 33}, {}, {},
+	}, frame)
+}
+
+func TestHorizontalPartialPixel(t *testing.T) {
+	frame := mapLayout(Horizontal{Count: 4}, []float64{0.375})
+	// 1.5 pixels lit
+	assert.Equal(t, []Color{{R: 1}, {R: 1, G: 17}, {}, {}}, frame)
+}
+
+func TestMatrixProgressive(t *testing.T) {
+	m := Matrix{Width: 3, Height: 2, Wiring: Progressive}
+	assert.Equal(t, 4, m.Index(1, 1))
+	frame := mapLayout(m, []float64{1, 0.5, 0})
+	// top row, then bottom row
+	assert.Equal(t, []Color{
+		{R: 1, G: 100}, {}, {},
+		{R: 1}, {R: 2}, {},
+	}, frame)
+}
+
+func TestMatrixSerpentine(t *testing.T) {
+	m := Matrix{Width: 3, Height: 2, Wiring: Serpentine}
+	assert.Equal(t, 4, m.Index(1, 1))
+	assert.Equal(t, 5, m.Index(0, 1))
+	frame := mapLayout(m, []float64{1, 0.5, 0})
+	// top row left to right, then bottom row right to left
+	assert.Equal(t, []Color{
+		{R: 1, G: 100}, {}, {},
+		{}, {R: 2}, {R: 1},
+	}, frame)
+}
+
+func TestMatrixWideBands(t *testing.T) {
+	frame := mapLayout(Matrix{Width: 4, Height: 1}, []float64{1, 0})
+	assert.Equal(t, []Color{{R: 1}, {R: 1}, {}, {}}, frame, "each band gets two columns")
+}
+
+func TestMirror(t *testing.T) {
+	frame := mapLayout(Mirror{Half: Horizontal{Count: 3}}, []float64{2.0 / 3})
+	assert.Equal(t, []Color{{}, {R: 1, G: 50}, {R: 1}, {R: 1}, {R: 1, G: 50}, {}}, frame)
+}
+
+func TestRing(t *testing.T) {
+	frame := mapLayout(Ring{Count: 8}, []float64{0.75, 0.5})
+	// arcs 0-3 and 4-7, growing from pixels 2 and 6
+	assert.Equal(t, []Color{
+		{}, {R: 1, G: 33}, {R: 1}, {R: 1, G: 66},
+		{}, {R: 2, G: 33}, {R: 2}, {},
+	}, frame)
+
+	frame = mapLayout(Ring{Count: 8, Offset: 3}, []float64{0.75, 0.5})
+	assert.Equal(t, Color{R: 1}, frame[5])
+	assert.Equal(t, Color{R: 2, G: 33}, frame[0])
+	assert.Equal(t, Color{R: 2}, frame[1])
+}
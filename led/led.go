@@ -0,0 +1,142 @@
+// Package led turns band values into frames of pixel colors for
+// addressable LED strips and matrices.
+package led
+
+import (
+	"fmt"
+	"math"
+)
+
+// Color is a pixel color. W is only sent to RGBW strips.
+type Color struct {
+	R, G, B, W uint8
+}
+
+// Scale dims c by f, from 0 (off) to 1 (unchanged).
+func (c Color) Scale(f float64) Color {
+	f = min(max(f, 0), 1)
+	scale := func(v uint8) uint8 {
+		return uint8(math.Round(float64(v) * f))
+	}
+	return Color{scale(c.R), scale(c.G), scale(c.B), scale(c.W)}
+}
+
+// Order is the order a strip expects the channels of each pixel, such as
+// "GRB" for WS2812 or "GRBW" for SK6812 RGBW.
+type Order string
+
+const (
+	RGB  Order = "RGB"
+	GRB  Order = "GRB"
+	BGR  Order = "BGR"
+	RGBW Order = "RGBW"
+	GRBW Order = "GRBW"
+)
+
+// ParseOrder accepts any arrangement of R, G and B, optionally with W.
+func ParseOrder(s string) (Order, error) {
+	o := Order(s)
+	if len(o) != 3 && len(o) != 4 {
+		return "", fmt.Errorf("invalid color order %q", s)
+	}
+	seen := map[rune]bool{}
+	for _, c := range o {
+		switch c {
+		case 'R', 'G', 'B', 'W':
+		default:
+			return "", fmt.Errorf("invalid color order %q", s)
+		}
+		if seen[c] {
+			return "", fmt.Errorf("invalid color order %q", s)
+		}
+		seen[c] = true
+	}
+	if !seen['R'] || !seen['G'] || !seen['B'] {
+		return "", fmt.Errorf("invalid color order %q", s)
+	}
+	return o, nil
+}
+
+// Channels is the number of bytes per pixel.
+func (o Order) Channels() int {
+	return len(o)
+}
+
+func (o Order) put(dst []byte, c Color) {
+	for i := range len(o) {
+		switch o[i] {
+		case 'R':
+			dst[i] = c.R
+		case 'G':
+			dst[i] = c.G
+		case 'B':
+			dst[i] = c.B
+		case 'W':
+			dst[i] = c.W
+		}
+	}
+}
+
+// Strip is a run of addressable pixels.
+type Strip struct {
+	Pixels int
+	Order  Order
+}
+
+// FrameSize is the number of bytes in an encoded frame.
+func (s Strip) FrameSize() int {
+	return s.Pixels * s.Order.Channels()
+}
+
+// AppendFrame encodes frame in the strip's channel order, appending it to
+// dst. Pixels past the end of frame are sent as off.
+func (s Strip) AppendFrame(dst []byte, frame []Color) []byte {
+	ch := s.Order.Channels()
+	start := len(dst)
+	dst = append(dst, make([]byte, s.FrameSize())...)
+	for i := range min(s.Pixels, len(frame)) {
+		s.Order.put(dst[start+i*ch:], frame[i])
+	}
+	return dst
+}
+
+// Mapper turns band values into a frame of colors for a strip. Values are
+// expected to be from 0 to 1, as after a clip stage, and are clamped.
+type Mapper struct {
+	Strip  Strip
+	Layout Layout
+	Colors Colorer
+	// Gamma, if set, corrects the colors for the LEDs
+	Gamma *Gamma
+	// Brightness limits every channel to this fraction of full, after gamma
+	// correction. Zero means no limit.
+	Brightness float64
+	// Power, if set, dims frames which would draw too much current
+	Power *PowerLimit
+
+	frame []Color
+}
+
+// Frame maps values onto the strip. The frame is reused by the next call.
+// Pixels past the end of the layout are left off.
+func (m *Mapper) Frame(values []float64) []Color {
+	if len(m.frame) != m.Strip.Pixels {
+		m.frame = make([]Color, m.Strip.Pixels)
+	}
+	clear(m.frame)
+	n := min(m.Layout.Pixels(), len(m.frame))
+	m.Layout.Map(values, m.Colors, m.frame[:n])
+	for i, c := range m.frame[:n] {
+		if m.Gamma != nil {
+			c = m.Gamma.Apply(c)
+		}
+		if m.Brightness > 0 && m.Brightness < 1 {
+			c = c.Scale(m.Brightness)
+		}
+		m.frame[i] = c
+	}
+	if m.Power != nil {
+		m.Power.Limit(m.frame)
+	}
+	return m.frame
+}
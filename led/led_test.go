@@ -0,0 +1,47 @@
+package led
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOrder(t *testing.T) {
+	for _, s := range []string{"RGB", "GRB", "BRG", "RGBW", "WGRB"} {
+		o, err := ParseOrder(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, Order(s), o)
+	}
+	for _, s := range []string{"", "RG", "RGBX", "RRG", "RGBWW", "RGW"} {
+		_, err := ParseOrder(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestAppendFrame(t *testing.T) {
+	frame := []Color{{R: 1, G: 2, B: 3, W: 4}, {R: 5, G: 6, B: 7, W: 8}}
+
+	s := Strip{Pixels: 3, Order: GRB}
+	assert.Equal(t, []byte{0xff, 2, 1, 3, 6, 5, 7, 0, 0, 0},
+		s.AppendFrame([]byte{0xff}, frame), "the last pixel is off")
+
+	s = Strip{Pixels: 2, Order: GRBW}
+	assert.Equal(t, []byte{2, 1, 3, 4, 6, 5, 7, 8}, s.AppendFrame(nil, frame))
+	assert.Equal(t, 8, s.FrameSize())
+}
+
+func TestScale(t *testing.T) {
+	c := Color{R: 255, G: 100, B: 1, W: 10}
+	assert.Equal(t, Color{R: 128, G: 50, B: 1, W: 5}, c.Scale(0.5))
+	assert.Equal(t, Color{}, c.Scale(-1))
+	assert.Equal(t, c, c.Scale(2))
+}
+
+func TestMapper(t *testing.T) {
+	m := Mapper{Strip: Strip{Pixels: 6, Order: RGB}, Layout: Horizontal{Count: 4}, Colors: Solid{R: 10}}
+	frame := m.Frame([]float64{1, 0.5})
+	assert.Equal(t, []Color{{R: 10}, {R: 10}, {R: 10}, {}, {}, {}}, frame, "pixels past the layout are off")
+
+	frame = m.Frame([]float64{0, 2})
+	assert.Equal(t, []Color{{}, {}, {R: 10}, {R: 10}, {}, {}}, frame, "cleared and clamped")
+}
@@ -0,0 +1,138 @@
+package led
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// OPCPort is the usual Open Pixel Control port.
+const OPCPort = 7890
+
+const (
+	opcSetPixels = 0
+	opcMaxPixels = 0xffff / 3
+
+	opcTimeout = time.Second
+	// opcRetry is how long to wait before reconnecting after a failure
+	opcRetry = time.Second
+)
+
+// OPC is a display which sends frames to an Open Pixel Control server, such
+// as Fadecandy's fcserver or a simulator, over TCP. It connects on the
+// first frame and reconnects if the connection drops, so the server can be
+// restarted while playing.
+type OPC struct {
+	addr    string
+	channel byte
+	mapper  *Mapper
+
+	conn    net.Conn
+	retryAt time.Time
+	lastErr error
+
+	buf []byte
+}
+
+// NewOPC creates a client for the server at address (host or host:port).
+// Channel 0 sends to every channel of the server.
+func NewOPC(address string, channel int, m *Mapper) (*OPC, error) {
+	if channel < 0 || channel > 255 {
+		return nil, fmt.Errorf("opc channel must be from 0 to 255")
+	}
+	if m.Strip.Pixels > opcMaxPixels {
+		return nil, fmt.Errorf("opc supports up to %d pixels, not %d", opcMaxPixels, m.Strip.Pixels)
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, strconv.Itoa(OPCPort))
+	}
+	return &OPC{addr: address, channel: byte(channel), mapper: m}, nil
+}
+
+func (o *OPC) Render(values []float64) error {
+	if o.conn == nil {
+		if time.Now().Before(o.retryAt) {
+			return o.lastErr
+		}
+		conn, err := net.DialTimeout("tcp", o.addr, opcTimeout)
+		if err != nil {
+			return o.fail(err)
+		}
+		o.conn = conn
+	}
+
+	// OPC pixels are always RGB; the server maps them to its strips
+	o.buf = append(o.buf[:0], o.channel, opcSetPixels, 0, 0)
+	o.buf = Strip{Pixels: o.mapper.Strip.Pixels, Order: RGB}.AppendFrame(o.buf, o.mapper.Frame(values))
+	binary.BigEndian.PutUint16(o.buf[2:], uint16(len(o.buf)-4))
+
+	o.conn.SetWriteDeadline(time.Now().Add(opcTimeout))
+	if _, err := o.conn.Write(o.buf); err != nil {
+		o.conn.Close()
+		o.conn = nil
+		return o.fail(err)
+	}
+	return nil
+}
+
+func (o *OPC) fail(err error) error {
+	o.lastErr = fmt.Errorf("opc: %w", err)
+	o.retryAt = time.Now().Add(opcRetry)
+	return o.lastErr
+}
+
+func (o *OPC) Close() error {
+	if o.conn == nil {
+		return nil
+	}
+	return o.conn.Close()
+}
+
+// ServeOPC accepts Open Pixel Control clients on l and calls handle with
+// each set pixel colors message, until l is closed. Other commands are
+// ignored. Each client is served on its own goroutine, so handle must be
+// safe to call concurrently, and must not keep pixels after returning.
+func ServeOPC(l net.Listener, handle func(channel byte, pixels []Color)) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go serveOPCConn(conn, handle)
+	}
+}
+
+func serveOPCConn(conn net.Conn, handle func(channel byte, pixels []Color)) {
+	defer conn.Close()
+	var header [4]byte
+	var data []byte
+	var pixels []Color
+	for {
+		if _, err := io.ReadFull(conn, header[:]); err != nil {
+			return
+		}
+		n := int(binary.BigEndian.Uint16(header[2:]))
+		if cap(data) < n {
+			data = make([]byte, n)
+		}
+		data = data[:n]
+		if _, err := io.ReadFull(conn, data); err != nil {
+			return
+		}
+		if header[1] != opcSetPixels {
+			continue
+		}
+		pixels = pixels[:0]
+		for i := 0; i+3 <= n; i += 3 {
+			pixels = append(pixels, Color{R: data[i], G: data[i+1], B: data[i+2]})
+		}
+		handle(header[0], pixels)
+	}
+}
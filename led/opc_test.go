@@ -0,0 +1,123 @@
+package led
+
+import (
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type opcMessage struct {
+	channel byte
+	pixels  []Color
+}
+
+// startOPCServer serves on a local port, returning its address and the
+// messages it receives
+func startOPCServer(t *testing.T) (string, <-chan opcMessage) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	msgs := make(chan opcMessage, 16)
+	go ServeOPC(l, func(channel byte, pixels []Color) {
+		msgs <- opcMessage{channel, slices.Clone(pixels)}
+	})
+	return l.Addr().String(), msgs
+}
+
+func receive(t *testing.T, msgs <-chan opcMessage) opcMessage {
+	select {
+	case m := <-msgs:
+		return m
+	case <-time.After(time.Second):
+		t.Fatal("no message received")
+		return opcMessage{}
+	}
+}
+
+func TestOPCLoop(t *testing.T) {
+	addr, msgs := startOPCServer(t)
+	m := dmxMapper(3)
+	m.Strip.Order = GRB // always sent as RGB
+	o, err := NewOPC(addr, 2, m)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer o.Close()
+
+	assert.NoError(t, o.Render([]float64{1}))
+	assert.NoError(t, o.Render([]float64{0}))
+	assert.Equal(t, opcMessage{2, []Color{{R: 1, G: 2, B: 3}, {R: 1, G: 2, B: 3}, {R: 1, G: 2, B: 3}}}, receive(t, msgs))
+	assert.Equal(t, opcMessage{2, []Color{{}, {}, {}}}, receive(t, msgs))
+}
+
+func TestOPCMessage(t *testing.T) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	o, err := NewOPC(l.Addr().String(), 0, dmxMapper(2))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer o.Close()
+
+	assert.NoError(t, o.Render([]float64{1}))
+	conn, err := l.Accept()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	buf := make([]byte, 10)
+	_, err = conn.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0, 0, 0, 6, 1, 2, 3, 1, 2, 3}, buf)
+}
+
+func TestOPCReconnect(t *testing.T) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	o, err := NewOPC(addr, 0, dmxMapper(1))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer o.Close()
+	assert.ErrorContains(t, o.Render([]float64{1}), "opc")
+
+	// the server comes up; after the retry delay frames get through
+	l, err = net.Listen("tcp4", addr)
+	if err != nil {
+		t.Skipf("port taken: %v", err)
+	}
+	defer l.Close()
+	msgs := make(chan opcMessage, 16)
+	go ServeOPC(l, func(channel byte, pixels []Color) {
+		msgs <- opcMessage{channel, slices.Clone(pixels)}
+	})
+	assert.Error(t, o.Render([]float64{1}), "waits before retrying")
+	o.retryAt = time.Time{}
+	assert.NoError(t, o.Render([]float64{1}))
+	assert.Equal(t, []Color{{R: 1, G: 2, B: 3}}, receive(t, msgs).pixels)
+}
+
+func TestServeOPCIgnoresOtherCommands(t *testing.T) {
+	addr, msgs := startOPCServer(t)
+	conn, err := net.Dial("tcp4", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte{1, 0xff, 0, 2, 0xaa, 0xbb}) // system exclusive
+	conn.Write([]byte{1, 0, 0, 3, 4, 5, 6})
+	assert.Equal(t, opcMessage{1, []Color{{R: 4, G: 5, B: 6}}}, receive(t, msgs))
+}
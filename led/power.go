@@ -0,0 +1,86 @@
+package led
+
+import "math"
+
+// PowerLimit estimates the current a frame draws and dims it to stay within
+// the supply's budget. For WS2812 pixels each channel draws about 20 mA at
+// full brightness and each pixel about 1 mA even when off.
+type PowerLimit struct {
+	ChannelMA float64 // per channel at full brightness
+	IdleMA    float64 // per pixel when off
+	Amps      float64 // budget
+	Volts     float64 // supply voltage, for reporting watts
+
+	// OnChange, if set, is called when limiting starts or stops, with the
+	// current the frame would have drawn unlimited
+	OnChange func(active bool, amps float64)
+
+	active  bool
+	limited uint64
+	scale   float64
+}
+
+// Current is the estimated draw of frame in amps.
+func (p *PowerLimit) Current(frame []Color) float64 {
+	return (p.idleMA(frame) + p.dynamicMA(frame)) / 1000
+}
+
+// Watts is the estimated power of frame.
+func (p *PowerLimit) Watts(frame []Color) float64 {
+	return p.Current(frame) * p.Volts
+}
+
+func (p *PowerLimit) idleMA(frame []Color) float64 {
+	return p.IdleMA * float64(len(frame))
+}
+
+func (p *PowerLimit) dynamicMA(frame []Color) float64 {
+	var sum int
+	for _, c := range frame {
+		sum += int(c.R) + int(c.G) + int(c.B) + int(c.W)
+	}
+	return float64(sum) / 255 * p.ChannelMA
+}
+
+// Limit dims frame in place so it draws no more than the budget, returning
+// the scale applied (1 when under budget).
+func (p *PowerLimit) Limit(frame []Color) float64 {
+	idle, dynamic := p.idleMA(frame), p.dynamicMA(frame)
+	budget := p.Amps*1000 - idle
+	scale := 1.0
+	// a dark frame can't be dimmed, even if the idle draw is over budget
+	if dynamic > 0 && idle+dynamic > p.Amps*1000 {
+		scale = max(budget, 0) / dynamic
+		// round down so the result stays within budget
+		dim := func(v uint8) uint8 {
+			return uint8(math.Floor(float64(v) * scale))
+		}
+		for i, c := range frame {
+			frame[i] = Color{dim(c.R), dim(c.G), dim(c.B), dim(c.W)}
+		}
+		p.limited++
+	}
+	p.scale = scale
+	if active := scale < 1; active != p.active {
+		p.active = active
+		if p.OnChange != nil {
+			p.OnChange(active, (idle+dynamic)/1000)
+		}
+	}
+	return scale
+}
+
+// Active reports whether the last frame was limited.
+func (p *PowerLimit) Active() bool {
+	return p.active
+}
+
+// Scale is the scale applied to the last frame.
+func (p *PowerLimit) Scale() float64 {
+	return p.scale
+}
+
+// Limited is the number of frames which have been dimmed.
+func (p *PowerLimit) Limited() uint64 {
+	return p.limited
+}
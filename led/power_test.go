@@ -0,0 +1,61 @@
+package led
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ws2812(amps float64) *PowerLimit {
+	return &PowerLimit{ChannelMA: 20, IdleMA: 1, Amps: amps, Volts: 5}
+}
+
+func TestPowerEstimate(t *testing.T) {
+	p := ws2812(100)
+	white := slices.Repeat([]Color{{R: 255, G: 255, B: 255}}, 300)
+	assert.InDelta(t, 18.3, p.Current(white), 1e-9)
+	assert.InDelta(t, 91.5, p.Watts(white), 1e-9)
+	assert.InDelta(t, 0.3, p.Current(make([]Color, 300)), 1e-9)
+}
+
+func TestPowerLimit(t *testing.T) {
+	var changes []bool
+	p := ws2812(0.05)
+	p.OnChange = func(active bool, amps float64) { changes = append(changes, active) }
+
+	// 10 idle + 2*20 = 50 mA, just within budget
+	frame := []Color{{R: 255, G: 255}, {}, {}, {}, {}, {}, {}, {}, {}, {}}
+	assert.Equal(t, 1.0, p.Limit(frame))
+	assert.False(t, p.Active())
+
+	// 10 idle + 3*3*20 = 190 mA, dynamic scaled to 40 mA
+	frame = []Color{{R: 255, G: 255, B: 255}, {R: 255, G: 255, B: 255}, {R: 255, G: 255, B: 255}, {}, {}, {}, {}, {}, {}, {}}
+	scale := p.Limit(frame)
+	assert.InDelta(t, 40.0/180, scale, 1e-9)
+	assert.Equal(t, Color{R: 56, G: 56, B: 56}, frame[0])
+	assert.LessOrEqual(t, p.Current(frame), 0.05)
+	assert.True(t, p.Active())
+	assert.Equal(t, uint64(1), p.Limited())
+
+	p.Limit(make([]Color, 10))
+	assert.Equal(t, []bool{true, false}, changes)
+}
+
+func TestPowerLimitIdleOverBudget(t *testing.T) {
+	p := ws2812(0.005)
+	frame := make([]Color, 10)
+	assert.Equal(t, 1.0, p.Limit(frame))
+	assert.Equal(t, make([]Color, 10), frame)
+	assert.False(t, p.Active())
+}
+
+func TestMapperPowerLimit(t *testing.T) {
+	m := Mapper{
+		Strip:  Strip{Pixels: 2, Order: RGB},
+		Layout: Horizontal{Count: 2},
+		Colors: Solid{R: 255, G: 255, B: 255},
+		Power:  &PowerLimit{ChannelMA: 20, Amps: 0.06},
+	}
+	assert.Equal(t, []byte{127, 127, 127, 127, 127, 127}, m.Strip.AppendFrame(nil, m.Frame([]float64{1})))
+}
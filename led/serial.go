@@ -0,0 +1,94 @@
+package led
+
+import (
+	"fmt"
+	"io"
+)
+
+// Protocol frames encoded pixel data for a serial link.
+type Protocol int
+
+const (
+	// Adalight is "Ada", the pixel count minus one as two bytes, a
+	// checksum of those, then the pixel data
+	Adalight Protocol = iota
+	// TPM2 is a 0xC9 0xDA header, the data length as two bytes, the data,
+	// then 0x36
+	TPM2
+)
+
+func ParseProtocol(s string) (Protocol, error) {
+	switch s {
+	case "adalight":
+		return Adalight, nil
+	case "tpm2":
+		return TPM2, nil
+	}
+	return 0, fmt.Errorf("unknown serial protocol %q", s)
+}
+
+func (p Protocol) String() string {
+	switch p {
+	case Adalight:
+		return "adalight"
+	case TPM2:
+		return "tpm2"
+	}
+	return fmt.Sprintf("Protocol(%d)", int(p))
+}
+
+// MaxPixels is the largest strip the protocol can address at channels bytes
+// per pixel.
+func (p Protocol) MaxPixels(channels int) int {
+	if p == TPM2 {
+		return 0xffff / channels
+	}
+	return 0x10000
+}
+
+// AppendFrame wraps data, holding pixels pixels, and appends it to dst.
+func (p Protocol) AppendFrame(dst, data []byte, pixels int) []byte {
+	switch p {
+	case Adalight:
+		hi, lo := byte((pixels-1)>>8), byte(pixels-1)
+		dst = append(dst, 'A', 'd', 'a', hi, lo, hi^lo^0x55)
+		return append(dst, data...)
+	case TPM2:
+		dst = append(dst, 0xc9, 0xda, byte(len(data)>>8), byte(len(data)))
+		dst = append(dst, data...)
+		return append(dst, 0x36)
+	}
+	panic(fmt.Sprintf("led: unknown protocol %d", int(p)))
+}
+
+// Serial is a display which sends each frame to a microcontroller over a
+// serial link, such as one opened with [OpenSerial].
+type Serial struct {
+	w        io.Writer
+	protocol Protocol
+	mapper   *Mapper
+
+	data, buf []byte
+}
+
+func NewSerial(w io.Writer, p Protocol, m *Mapper) (*Serial, error) {
+	if limit := p.MaxPixels(m.Strip.Order.Channels()); m.Strip.Pixels < 1 || m.Strip.Pixels > limit {
+		return nil, fmt.Errorf("%v supports 1 to %d pixels, not %d", p, limit, m.Strip.Pixels)
+	}
+	return &Serial{w: w, protocol: p, mapper: m}, nil
+}
+
+func (s *Serial) Render(values []float64) error {
+	s.data = s.mapper.Strip.AppendFrame(s.data[:0], s.mapper.Frame(values))
+	s.buf = s.protocol.AppendFrame(s.buf[:0], s.data, s.mapper.Strip.Pixels)
+	_, err := s.w.Write(s.buf)
+	return err
+}
+
+// Close closes the writer, if it can be.
+func (s *Serial) Close() error {
+	if c, ok := s.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
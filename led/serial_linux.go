@@ -0,0 +1,60 @@
+package led
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+var bauds = map[int]uint32{
+	9600:    unix.B9600,
+	19200:   unix.B19200,
+	38400:   unix.B38400,
+	57600:   unix.B57600,
+	115200:  unix.B115200,
+	230400:  unix.B230400,
+	460800:  unix.B460800,
+	500000:  unix.B500000,
+	921600:  unix.B921600,
+	1000000: unix.B1000000,
+	2000000: unix.B2000000,
+}
+
+// OpenSerial opens a serial device for writing raw bytes at baud. A baud of
+// 0 keeps the device's current speed.
+func OpenSerial(path string, baud int) (io.WriteCloser, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := configure(int(f.Fd()), baud); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
+
+func configure(fd, baud int) error {
+	t, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return err
+	}
+	// raw 8N1, so no bytes are translated
+	t.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	t.Oflag &^= unix.OPOST
+	t.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	t.Cflag &^= unix.CSIZE | unix.PARENB | unix.CSTOPB
+	t.Cflag |= unix.CS8 | unix.CLOCAL | unix.CREAD
+	if baud != 0 {
+		speed, ok := bauds[baud]
+		if !ok {
+			return fmt.Errorf("unsupported baud rate %d", baud)
+		}
+		t.Cflag &^= unix.CBAUD
+		t.Cflag |= speed
+		t.Ispeed, t.Ospeed = speed, speed
+	}
+	return unix.IoctlSetTermios(fd, unix.TCSETS, t)
+}
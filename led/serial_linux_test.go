@@ -0,0 +1,56 @@
+package led
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+// openPty returns the master side of a new pseudo-terminal and the path of
+// its serial end
+func openPty(t *testing.T) (*os.File, string) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		t.Skipf("no pty: %v", err)
+	}
+	t.Cleanup(func() { master.Close() })
+	if err := unix.IoctlSetPointerInt(int(master.Fd()), unix.TIOCSPTLCK, 0); err != nil {
+		t.Skipf("no pty: %v", err)
+	}
+	n, err := unix.IoctlGetInt(int(master.Fd()), unix.TIOCGPTN)
+	if err != nil {
+		t.Skipf("no pty: %v", err)
+	}
+	return master, fmt.Sprintf("/dev/pts/%d", n)
+}
+
+func TestOpenSerialPty(t *testing.T) {
+	master, path := openPty(t)
+	w, err := OpenSerial(path, 115200)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer w.Close()
+
+	// newlines and carriage returns must pass through untranslated
+	s, err := NewSerial(w, Adalight, &Mapper{
+		Strip:  Strip{Pixels: 2, Order: RGB},
+		Layout: Horizontal{Count: 2},
+		Colors: Solid{R: '\n', G: '\r', B: 0x7f},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, s.Render([]float64{1}))
+
+	expected := []byte{'A', 'd', 'a', 0, 1, 0x54, '\n', '\r', 0x7f, '\n', '\r', 0x7f}
+	got := make([]byte, len(expected))
+	_, err = io.ReadFull(master, got)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, got)
+
+	_, err = OpenSerial(path, 12345)
+	assert.ErrorContains(t, err, "baud")
+}
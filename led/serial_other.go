@@ -0,0 +1,19 @@
+//go:build !linux
+
+package led
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// OpenSerial opens a serial device for writing. Only Linux can set the
+// baud rate, so elsewhere configure the device beforehand (with stty, for
+// example) and pass a baud of 0.
+func OpenSerial(path string, baud int) (io.WriteCloser, error) {
+	if baud != 0 {
+		return nil, errors.New("setting the baud rate is only supported on linux")
+	}
+	return os.OpenFile(path, os.O_WRONLY, 0)
+}
@@ -0,0 +1,68 @@
+package led
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testMapper(pixels int) *Mapper {
+	return &Mapper{
+		Strip:  Strip{Pixels: pixels, Order: RGB},
+		Layout: Horizontal{Count: pixels},
+		Colors: ByBand{Gradient: EvenGradient(Color{R: 255}, Color{B: 255})},
+	}
+}
+
+func TestAdalight(t *testing.T) {
+	var buf bytes.Buffer
+	s, err := NewSerial(&buf, Adalight, testMapper(3))
+	assert.NoError(t, err)
+
+	assert.NoError(t, s.Render([]float64{1, 0.5}))
+	assert.Equal(t, []byte{
+		'A', 'd', 'a', 0x00, 0x02, 0x57, // 3 pixels, checksum 0 ^ 2 ^ 0x55
+		255, 0, 0, // first band, 1 pixel
+		0, 0, 255, // second band, half of 2 pixels
+		0, 0, 0,
+	}, buf.Bytes())
+}
+
+func TestAdalightChecksum(t *testing.T) {
+	frame := Adalight.AppendFrame(nil, nil, 300)
+	assert.Equal(t, []byte{'A', 'd', 'a', 0x01, 0x2b, 0x01 ^ 0x2b ^ 0x55}, frame)
+}
+
+func TestTPM2(t *testing.T) {
+	var buf bytes.Buffer
+	s, err := NewSerial(&buf, TPM2, testMapper(2))
+	assert.NoError(t, err)
+
+	assert.NoError(t, s.Render([]float64{1, 1}))
+	assert.NoError(t, s.Render([]float64{0, 1}))
+	assert.Equal(t, []byte{
+		0xc9, 0xda, 0x00, 0x06, 255, 0, 0, 0, 0, 255, 0x36,
+		0xc9, 0xda, 0x00, 0x06, 0, 0, 0, 0, 0, 255, 0x36,
+	}, buf.Bytes())
+}
+
+func TestSerialTooManyPixels(t *testing.T) {
+	_, err := NewSerial(nil, TPM2, testMapper(30_000))
+	assert.Error(t, err)
+	_, err = NewSerial(nil, Adalight, testMapper(0))
+	assert.Error(t, err)
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("unplugged")
+}
+
+func TestSerialWriteError(t *testing.T) {
+	s, err := NewSerial(failingWriter{}, Adalight, testMapper(1))
+	assert.NoError(t, err)
+	assert.ErrorContains(t, s.Render([]float64{1}), "unplugged")
+}
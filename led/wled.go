@@ -0,0 +1,194 @@
+package led
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"time"
+)
+
+// WLEDProtocol is a WLED realtime UDP format, or DDP.
+type WLEDProtocol int
+
+const (
+	// WARLS sends an index with each pixel, for up to 256 pixels
+	WARLS WLEDProtocol = 1
+	// DRGB sends every pixel in order, up to 490
+	DRGB WLEDProtocol = 2
+	// DRGBW is DRGB with white, up to 367 pixels
+	DRGBW WLEDProtocol = 3
+	// DNRGB sends pixels from a start index, so any strip can be sent in
+	// chunks
+	DNRGB WLEDProtocol = 4
+	// DDP is the Distributed Display Protocol, which WLED and many other
+	// controllers accept, sent in chunks
+	DDP WLEDProtocol = 0x100
+)
+
+var wledProtocols = map[string]WLEDProtocol{
+	"warls": WARLS,
+	"drgb":  DRGB,
+	"drgbw": DRGBW,
+	"dnrgb": DNRGB,
+	"ddp":   DDP,
+}
+
+func ParseWLEDProtocol(s string) (WLEDProtocol, error) {
+	if p, ok := wledProtocols[s]; ok {
+		return p, nil
+	}
+	return 0, fmt.Errorf("unknown WLED protocol %q", s)
+}
+
+func (p WLEDProtocol) String() string {
+	for name, q := range wledProtocols {
+		if p == q {
+			return name
+		}
+	}
+	return fmt.Sprintf("WLEDProtocol(%d)", int(p))
+}
+
+const (
+	wledPort = 21324
+	ddpPort  = 4048
+
+	dnrgbChunk = 489  // pixels per DNRGB packet
+	ddpChunk   = 1440 // bytes per DDP packet, a whole number of pixels
+
+	// DefaultWLEDTimeout is how long WLED waits after the last frame
+	// before going back to its own effects
+	DefaultWLEDTimeout = 2 * time.Second
+)
+
+// WLEDOptions configures a [WLED] output.
+type WLEDOptions struct {
+	// Address is the receiver as host or host:port
+	Address string
+	// Timeout is how long the receiver waits after the last frame before
+	// going back to normal, in whole seconds. Zero is [DefaultWLEDTimeout]
+	// and 255s or more waits until it is rebooted. DDP has no timeout.
+	Timeout time.Duration
+}
+
+// WLED is a display which sends frames to WLED over UDP. Colors are always
+// sent as RGB (or RGBW), since WLED reorders them for its strip.
+type WLED struct {
+	protocol WLEDProtocol
+	mapper   *Mapper
+	timeout  byte
+
+	conn  net.Conn
+	strip Strip // the mapper's strip, in WLED's order
+	seq   byte
+
+	data, pkt []byte
+}
+
+func NewWLED(p WLEDProtocol, m *Mapper, opts WLEDOptions) (*WLED, error) {
+	if opts.Address == "" {
+		return nil, fmt.Errorf("%v needs an address", p)
+	}
+	w := &WLED{protocol: p, mapper: m, strip: Strip{Pixels: m.Strip.Pixels, Order: RGB}}
+	if p == DRGBW || (p == DDP && m.Strip.Order.Channels() == 4) {
+		w.strip.Order = RGBW
+	}
+	if limit := map[WLEDProtocol]int{WARLS: 256, DRGB: 490, DRGBW: 367}[p]; limit > 0 && m.Strip.Pixels > limit {
+		return nil, fmt.Errorf("%v supports up to %d pixels, not %d; use dnrgb or ddp", p, limit, m.Strip.Pixels)
+	}
+
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = DefaultWLEDTimeout
+	}
+	w.timeout = byte(min(math.Ceil(timeout.Seconds()), 255))
+
+	addr := opts.Address
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		port := wledPort
+		if p == DDP {
+			port = ddpPort
+		}
+		addr = net.JoinHostPort(addr, strconv.Itoa(port))
+	}
+	conn, err := net.Dial("udp4", addr)
+	if err != nil {
+		return nil, err
+	}
+	w.conn = conn
+	return w, nil
+}
+
+func (w *WLED) Render(values []float64) error {
+	frame := w.mapper.Frame(values)
+	w.data = w.strip.AppendFrame(w.data[:0], frame)
+	switch w.protocol {
+	case WARLS:
+		w.pkt = append(w.pkt[:0], byte(WARLS), w.timeout)
+		for i, c := range frame {
+			w.pkt = append(w.pkt, byte(i), c.R, c.G, c.B)
+		}
+		return w.send(w.pkt)
+	case DRGB, DRGBW:
+		w.pkt = append(append(w.pkt[:0], byte(w.protocol), w.timeout), w.data...)
+		return w.send(w.pkt)
+	case DNRGB:
+		for start := 0; start < len(frame); start += dnrgbChunk {
+			end := min(start+dnrgbChunk, len(frame))
+			w.pkt = append(w.pkt[:0], byte(DNRGB), w.timeout, byte(start>>8), byte(start))
+			w.pkt = append(w.pkt, w.data[start*3:end*3]...)
+			if err := w.send(w.pkt); err != nil {
+				return err
+			}
+		}
+	case DDP:
+		return w.sendDDP()
+	}
+	return nil
+}
+
+// DDP header fields
+const (
+	ddpVersion1 = 0x40
+	ddpPush     = 0x01
+	ddpRGB24    = 0x0b
+	ddpRGBW32   = 0x1b
+	ddpDisplay  = 0x01 // default output device
+)
+
+// sendDDP sends the frame in chunks, with a push on the last telling the
+// receiver to show it.
+func (w *WLED) sendDDP() error {
+	w.seq = w.seq%15 + 1 // 0 means unsequenced
+	dataType := byte(ddpRGB24)
+	if w.strip.Order.Channels() == 4 {
+		dataType = ddpRGBW32
+	}
+	chunk := ddpChunk - ddpChunk%w.strip.Order.Channels()
+	for offset := 0; offset < len(w.data); offset += chunk {
+		end := min(offset+chunk, len(w.data))
+		flags := byte(ddpVersion1)
+		if end == len(w.data) {
+			flags |= ddpPush
+		}
+		w.pkt = append(w.pkt[:0], flags, w.seq, dataType, ddpDisplay)
+		w.pkt = binary.BigEndian.AppendUint32(w.pkt, uint32(offset))
+		w.pkt = binary.BigEndian.AppendUint16(w.pkt, uint16(end-offset))
+		w.pkt = append(w.pkt, w.data[offset:end]...)
+		if err := w.send(w.pkt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *WLED) send(pkt []byte) error {
+	_, err := w.conn.Write(pkt)
+	return err
+}
+
+func (w *WLED) Close() error {
+	return w.conn.Close()
+}
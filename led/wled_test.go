@@ -0,0 +1,113 @@
+package led
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWARLS(t *testing.T) {
+	addr, next := listenUDP(t)
+	w, err := NewWLED(WARLS, dmxMapper(3), WLEDOptions{Address: addr, Timeout: 1500 * time.Millisecond})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer w.Close()
+
+	assert.NoError(t, w.Render([]float64{1}))
+	assert.Equal(t, []byte{1, 2, 0, 1, 2, 3, 1, 1, 2, 3, 2, 1, 2, 3}, next(), "timeout rounded up")
+}
+
+func TestDRGB(t *testing.T) {
+	addr, next := listenUDP(t)
+	m := dmxMapper(2)
+	m.Strip.Order = GRB // sent as RGB regardless
+	w, err := NewWLED(DRGB, m, WLEDOptions{Address: addr})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer w.Close()
+
+	assert.NoError(t, w.Render([]float64{0.5}))
+	assert.Equal(t, []byte{2, 2, 1, 2, 3, 0, 0, 0}, next())
+}
+
+func TestDRGBW(t *testing.T) {
+	addr, next := listenUDP(t)
+	m := dmxMapper(1)
+	m.Colors = Solid{R: 1, G: 2, B: 3, W: 4}
+	w, err := NewWLED(DRGBW, m, WLEDOptions{Address: addr, Timeout: time.Hour})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer w.Close()
+
+	assert.NoError(t, w.Render([]float64{1}))
+	assert.Equal(t, []byte{3, 255, 1, 2, 3, 4}, next(), "no timeout")
+}
+
+func TestDNRGBChunks(t *testing.T) {
+	addr, next := listenUDP(t)
+	w, err := NewWLED(DNRGB, dmxMapper(1000), WLEDOptions{Address: addr})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer w.Close()
+
+	assert.NoError(t, w.Render([]float64{1}))
+	for _, chunk := range []struct{ start, n int }{{0, 489}, {489, 489}, {978, 22}} {
+		pkt := next()
+		assert.Equal(t, []byte{4, 2, byte(chunk.start >> 8), byte(chunk.start)}, pkt[:4])
+		assert.Len(t, pkt, 4+3*chunk.n)
+		assert.Equal(t, []byte{1, 2, 3}, pkt[4:7])
+	}
+}
+
+func TestDDP(t *testing.T) {
+	addr, next := listenUDP(t)
+	w, err := NewWLED(DDP, dmxMapper(500), WLEDOptions{Address: addr})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer w.Close()
+
+	assert.NoError(t, w.Render([]float64{1}))
+	first, last := next(), next()
+	assert.Equal(t, []byte{0x40, 1, 0x0b, 1, 0, 0, 0, 0, 0x05, 0xa0}, first[:10])
+	assert.Len(t, first, 10+1440)
+	assert.Equal(t, []byte{0x41, 1, 0x0b, 1, 0, 0, 0x05, 0xa0, 0, 60}, last[:10], "pushed")
+	assert.Equal(t, []byte{1, 2, 3}, last[10:13])
+	assert.Len(t, last, 10+60)
+
+	for range 15 {
+		assert.NoError(t, w.Render([]float64{1}))
+		next()
+		next()
+	}
+	assert.NoError(t, w.Render([]float64{1}))
+	assert.Equal(t, byte(2), next()[1], "sequence wraps from 15 to 1")
+}
+
+func TestDDPRGBW(t *testing.T) {
+	addr, next := listenUDP(t)
+	m := dmxMapper(1)
+	m.Strip.Order = GRBW
+	w, err := NewWLED(DDP, m, WLEDOptions{Address: addr})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer w.Close()
+
+	assert.NoError(t, w.Render([]float64{1}))
+	assert.Equal(t, []byte{0x41, 1, 0x1b, 1, 0, 0, 0, 0, 0, 4, 1, 2, 3, 0}, next())
+}
+
+func TestWLEDLimits(t *testing.T) {
+	_, err := NewWLED(WARLS, dmxMapper(257), WLEDOptions{Address: "127.0.0.1"})
+	assert.ErrorContains(t, err, "dnrgb")
+	_, err = NewWLED(DRGB, dmxMapper(491), WLEDOptions{Address: "127.0.0.1"})
+	assert.Error(t, err)
+	_, err = NewWLED(DRGB, dmxMapper(1), WLEDOptions{})
+	assert.ErrorContains(t, err, "address")
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/rabidaudio/led-eq/metrics"
+)
+
+// pipelineMetrics measures each stage of the pipeline, to find what is
+// holding it up: decoding, computing the EQ, or a display. A nil
+// *pipelineMetrics measures nothing.
+type pipelineMetrics struct {
+	reg       *metrics.Registry
+	frames    *metrics.Counter
+	compute   *metrics.Histogram
+	decode    *metrics.Histogram
+	underruns *metrics.Counter
+	late      *metrics.Counter
+}
+
+func newPipelineMetrics(reg *metrics.Registry) *pipelineMetrics {
+	return &pipelineMetrics{
+		reg: reg,
+		frames: reg.Counter("ledeq_frames_computed_total",
+			"Frames computed by the EQ.", nil),
+		compute: reg.Histogram("ledeq_compute_seconds",
+			"Time to compute a frame, including post-processing.", nil, metrics.DurationBuckets),
+		decode: reg.Histogram("ledeq_decode_seconds",
+			"Time to read each chunk of audio from the input.", nil, metrics.DurationBuckets),
+		underruns: reg.Counter("ledeq_audio_underruns_total",
+			"Chunks of audio which took longer to produce than to play, so the output ran dry.", nil),
+		late: reg.Counter("ledeq_frames_late_total",
+			"Frames skipped because they were overdue after the delay.", nil),
+	}
+}
+
+// watchGain reports the gain of the stream.
+func (m *pipelineMetrics) watchGain(sw *EQStreamWrapper) {
+	if m == nil {
+		return
+	}
+	m.reg.GaugeFunc("ledeq_gain", "The normalization gain applied to the bands.", nil, sw.Gain)
+}
+
+func (m *pipelineMetrics) decoded(d time.Duration) {
+	if m != nil {
+		m.decode.Observe(d.Seconds())
+	}
+}
+
+func (m *pipelineMetrics) computed(d time.Duration) {
+	if m != nil {
+		m.frames.Inc()
+		m.compute.Observe(d.Seconds())
+	}
+}
+
+// streamed records a chunk of audio which took that long to produce, counting
+// an underrun if it took longer than its audio lasts. This is an estimate,
+// as the output's buffering isn't known.
+func (m *pipelineMetrics) streamed(took, audio time.Duration) {
+	if m != nil && took > audio {
+		m.underruns.Inc()
+	}
+}
+
+// measure wraps the display at index i of the config to time its renders
+// and count its errors. The series carry on when the display is rebuilt.
+func (m *pipelineMetrics) measure(i int, typ string, d Display) Display {
+	if m == nil {
+		return d
+	}
+	labels := metrics.Labels{"display": strconv.Itoa(i), "type": typ}
+	return &measuredDisplay{
+		Display: d,
+		render: m.reg.Histogram("ledeq_display_render_seconds",
+			"Time each display takes to render a frame.", labels, metrics.DurationBuckets),
+		rendered: m.reg.Counter("ledeq_display_frames_rendered_total",
+			"Frames rendered by each display.", labels),
+		errors: m.reg.Counter("ledeq_display_errors_total",
+			"Frames each display failed to render.", labels),
+		dropped: m.reg.Counter("ledeq_display_frames_dropped_total",
+			"Frames lost because the display fell behind.", labels),
+	}
+}
+
+// dropped counts frames dropped before reaching a display; it is the
+// OnDrop of the display wrappers.
+func (m *pipelineMetrics) dropped(d Display, n uint64) {
+	if md, ok := d.(*measuredDisplay); ok {
+		md.dropped.Add(n)
+	} else {
+		m.late.Add(n)
+	}
+}
+
+// measuredDisplay times a display's renders for [pipelineMetrics].
+type measuredDisplay struct {
+	Display
+	render   *metrics.Histogram
+	rendered *metrics.Counter
+	errors   *metrics.Counter
+	dropped  *metrics.Counter
+}
+
+func (md *measuredDisplay) Render(values []float64) error {
+	start := time.Now()
+	err := md.Display.Render(values)
+	md.render.Observe(time.Since(start).Seconds())
+	if err != nil {
+		md.errors.Inc()
+	} else {
+		md.rendered.Inc()
+	}
+	return err
+}
+
+// Close closes the display, if it can be.
+func (md *measuredDisplay) Close() error {
+	if c, ok := md.Display.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,293 @@
+// Package metrics keeps counters, gauges and histograms and serves them in
+// the Prometheus text format.
+package metrics
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Labels tell apart the series of a metric, such as each display.
+type Labels map[string]string
+
+// key is the labels in the text format, sorted by name, which also
+// identifies the series.
+func (l Labels) key() string {
+	if len(l) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(l))
+	for n := range l {
+		names = append(names, n)
+	}
+	slices.Sort(names)
+	var b strings.Builder
+	for i, n := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=\"%s\"", n, escape(l[n]))
+	}
+	return b.String()
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+// Registry holds metrics. Asking for a metric which already exists, with
+// the same labels, returns the existing one, so counts carry on when
+// whatever they measure is rebuilt.
+type Registry struct {
+	mu       sync.Mutex
+	families []*family
+}
+
+type family struct {
+	name, help, typ string
+	series          map[string]series // by labels
+}
+
+type series interface {
+	write(w *bufio.Writer, name, labels string)
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// get returns the series of name with labels, creating it with create if
+// needed.
+func (r *Registry) get(name, help, typ string, labels Labels, create func() series) series {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var f *family
+	for _, g := range r.families {
+		if g.name == name {
+			f = g
+		}
+	}
+	if f == nil {
+		f = &family{name: name, help: help, typ: typ, series: make(map[string]series)}
+		r.families = append(r.families, f)
+	}
+	if f.typ != typ {
+		panic(fmt.Sprintf("metrics: %s is a %s, not a %s", name, f.typ, typ))
+	}
+	key := labels.key()
+	s, ok := f.series[key]
+	if !ok {
+		s = create()
+		f.series[key] = s
+	}
+	return s
+}
+
+// Counter only goes up.
+type Counter struct {
+	v atomic.Uint64
+}
+
+func (r *Registry) Counter(name, help string, labels Labels) *Counter {
+	return r.get(name, help, "counter", labels, func() series { return &Counter{} }).(*Counter)
+}
+
+func (c *Counter) Inc() {
+	c.v.Add(1)
+}
+
+func (c *Counter) Add(n uint64) {
+	c.v.Add(n)
+}
+
+func (c *Counter) Value() uint64 {
+	return c.v.Load()
+}
+
+func (c *Counter) write(w *bufio.Writer, name, labels string) {
+	writeSample(w, name, labels, float64(c.Value()))
+}
+
+// Gauge is a value which can go up and down.
+type Gauge struct {
+	bits atomic.Uint64
+}
+
+func (r *Registry) Gauge(name, help string, labels Labels) *Gauge {
+	return r.get(name, help, "gauge", labels, func() series { return &Gauge{} }).(*Gauge)
+}
+
+func (g *Gauge) Set(v float64) {
+	g.bits.Store(math.Float64bits(v))
+}
+
+func (g *Gauge) Value() float64 {
+	return math.Float64frombits(g.bits.Load())
+}
+
+func (g *Gauge) write(w *bufio.Writer, name, labels string) {
+	writeSample(w, name, labels, g.Value())
+}
+
+// gaugeFunc reads its value when scraped.
+type gaugeFunc struct {
+	mu sync.Mutex
+	f  func() float64
+}
+
+// GaugeFunc reports f's value when scraped. Registering it again replaces
+// f.
+func (r *Registry) GaugeFunc(name, help string, labels Labels, f func() float64) {
+	g := r.get(name, help, "gauge", labels, func() series { return &gaugeFunc{} }).(*gaugeFunc)
+	g.mu.Lock()
+	g.f = f
+	g.mu.Unlock()
+}
+
+func (g *gaugeFunc) write(w *bufio.Writer, name, labels string) {
+	g.mu.Lock()
+	v := g.f()
+	g.mu.Unlock()
+	writeSample(w, name, labels, v)
+}
+
+// Histogram counts observations in buckets.
+type Histogram struct {
+	mu      sync.Mutex
+	buckets []float64 // upper bounds
+	counts  []uint64  // per bucket, not cumulative, with +Inf last
+	sum     float64
+}
+
+// DurationBuckets suit durations in seconds from 100µs to 1s.
+var DurationBuckets = []float64{0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1}
+
+// Histogram gets a histogram with the given bucket upper bounds, which
+// must be increasing.
+func (r *Registry) Histogram(name, help string, labels Labels, buckets []float64) *Histogram {
+	return r.get(name, help, "histogram", labels, func() series {
+		return &Histogram{buckets: buckets, counts: make([]uint64, len(buckets)+1)}
+	}).(*Histogram)
+}
+
+func (h *Histogram) Observe(v float64) {
+	i, _ := slices.BinarySearch(h.buckets, v)
+	h.mu.Lock()
+	h.counts[i]++
+	h.sum += v
+	h.mu.Unlock()
+}
+
+// Count is the number of observations.
+func (h *Histogram) Count() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var n uint64
+	for _, c := range h.counts {
+		n += c
+	}
+	return n
+}
+
+func (h *Histogram) write(w *bufio.Writer, name, labels string) {
+	h.mu.Lock()
+	counts := slices.Clone(h.counts)
+	sum := h.sum
+	h.mu.Unlock()
+	sep := ""
+	if labels != "" {
+		sep = ","
+	}
+	var n uint64
+	for i, c := range counts {
+		n += c
+		le := "+Inf"
+		if i < len(h.buckets) {
+			le = strconv.FormatFloat(h.buckets[i], 'g', -1, 64)
+		}
+		writeSample(w, name+"_bucket", labels+sep+`le="`+le+`"`, float64(n))
+	}
+	writeSample(w, name+"_sum", labels, sum)
+	writeSample(w, name+"_count", labels, float64(n))
+}
+
+func writeSample(w *bufio.Writer, name, labels string, v float64) {
+	w.WriteString(name)
+	if labels != "" {
+		w.WriteString("{" + labels + "}")
+	}
+	w.WriteByte(' ')
+	w.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+	w.WriteByte('\n')
+}
+
+// WriteText writes every metric in the Prometheus text format, series in
+// order of their labels.
+func (r *Registry) WriteText(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, f := range r.families {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.typ)
+		keys := make([]string, 0, len(f.series))
+		for k := range f.series {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			f.series[k].write(bw, f.name, k)
+		}
+	}
+	return bw.Flush()
+}
+
+// ServeHTTP serves the metrics to a scraper.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteText(w)
+}
+
+// Server serves a registry over HTTP at /metrics.
+type Server struct {
+	http     *http.Server
+	listener net.Listener
+}
+
+// NewServer starts serving r on addr (host:port).
+func NewServer(addr string, r *Registry) (*Server, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", r)
+	s := &Server{listener: l, http: &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}}
+	go s.http.Serve(l)
+	return s, nil
+}
+
+// Addr is the address the server is listening on.
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+func (s *Server) Close() error {
+	err := s.http.Close()
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+	return err
+}
@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func failIfErr(t *testing.T, err error) {
+	assert.NoError(t, err)
+	if err != nil {
+		t.FailNow()
+	}
+}
+
+func text(t *testing.T, r *Registry) string {
+	var b strings.Builder
+	failIfErr(t, r.WriteText(&b))
+	return b.String()
+}
+
+func TestCounterAndGauge(t *testing.T) {
+	r := NewRegistry()
+	c := r.Counter("frames_total", "Frames.", nil)
+	c.Inc()
+	c.Add(2)
+	r.Gauge("gain", "Gain.", nil).Set(1.5)
+	r.GaugeFunc("level", "Level.", nil, func() float64 { return 0.25 })
+
+	assert.Equal(t, `# HELP frames_total Frames.
+# TYPE frames_total counter
+frames_total 3
+# HELP gain Gain.
+# TYPE gain gauge
+gain 1.5
+# HELP level Level.
+# TYPE level gauge
+level 0.25
+`, text(t, r))
+}
+
+func TestLabels(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("errors_total", "Errors.", Labels{"type": "wled", "display": "1"}).Inc()
+	r.Counter("errors_total", "Errors.", Labels{"type": `a"b`, "display": "0"}).Add(2)
+	// the same labels get the same series
+	r.Counter("errors_total", "Errors.", Labels{"display": "1", "type": "wled"}).Inc()
+
+	assert.Equal(t, `# HELP errors_total Errors.
+# TYPE errors_total counter
+errors_total{display="0",type="a\"b"} 2
+errors_total{display="1",type="wled"} 2
+`, text(t, r))
+}
+
+func TestHistogram(t *testing.T) {
+	r := NewRegistry()
+	h := r.Histogram("render_seconds", "Render time.", Labels{"display": "0"}, []float64{0.1, 1})
+	for _, v := range []float64{0.05, 0.1, 0.5, 2} {
+		h.Observe(v)
+	}
+	assert.Equal(t, uint64(4), h.Count())
+	assert.Equal(t, `# HELP render_seconds Render time.
+# TYPE render_seconds histogram
+render_seconds_bucket{display="0",le="0.1"} 2
+render_seconds_bucket{display="0",le="1"} 3
+render_seconds_bucket{display="0",le="+Inf"} 4
+render_seconds_sum{display="0"} 2.65
+render_seconds_count{display="0"} 4
+`, text(t, r))
+}
+
+func TestWrongType(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("x", "X.", nil)
+	assert.Panics(t, func() { r.Gauge("x", "X.", nil) })
+}
+
+func TestServer(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("frames_total", "Frames.", nil).Inc()
+	s, err := NewServer("127.0.0.1:0", r)
+	failIfErr(t, err)
+	defer s.Close()
+
+	res, err := http.Get("http://" + s.Addr().String() + "/metrics")
+	failIfErr(t, err)
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	failIfErr(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Contains(t, res.Header.Get("Content-Type"), "version=0.0.4")
+	assert.Contains(t, string(body), "frames_total 1\n")
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rabidaudio/led-eq/eq"
+	"github.com/rabidaudio/led-eq/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamMetrics(t *testing.T) {
+	reg := metrics.NewRegistry()
+	m := newPipelineMetrics(reg)
+	e := eq.New(48_000, 1024, 8)
+	e.Normalize = 2
+	d := &recordingDisplay{}
+	wrap := EQStreamWrapper{Streamer: sine(440, 48_000, 48_000), eq: &e, d: d, metrics: m}
+	m.watchGain(&wrap)
+	assert.NoError(t, Pace(&wrap, 48_000, 512, AsFastAsPossible))
+
+	assert.Equal(t, uint64(len(d.frames)), m.frames.Value())
+	assert.Equal(t, uint64(len(d.frames)), m.compute.Count())
+	assert.Equal(t, uint64(48_000/512+1), m.decode.Count())
+	assert.Contains(t, text(t, reg), "ledeq_gain 2\n")
+}
+
+func TestDisplayMetrics(t *testing.T) {
+	reg := metrics.NewRegistry()
+	m := newPipelineMetrics(reg)
+	md := NewMultiDisplay(DropOnError, 10, m.measure(0, "test", &syncRecorder{}), m.measure(1, "test", failingDisplay{}))
+	md.OnDrop = m.dropped
+	for range 5 {
+		assert.NoError(t, md.Render([]float64{1}))
+	}
+	md.Close()
+	<-md.Done()
+
+	out := text(t, reg)
+	assert.Contains(t, out, `ledeq_display_frames_rendered_total{display="0",type="test"} 5`)
+	assert.Contains(t, out, `ledeq_display_errors_total{display="1",type="test"} 5`)
+	assert.Contains(t, out, `ledeq_display_render_seconds_count{display="1",type="test"} 5`)
+	assert.Contains(t, out, `ledeq_display_frames_dropped_total{display="0",type="test"} 0`)
+}
+
+func TestDroppedFrames(t *testing.T) {
+	m := newPipelineMetrics(metrics.NewRegistry())
+	blocked := &blockedDisplay{release: make(chan struct{})}
+	slow := m.measure(0, "test", blocked).(*measuredDisplay)
+	md := NewMultiDisplay(DropOnError, 1, slow)
+	md.OnDrop = m.dropped
+	for range 5 {
+		assert.NoError(t, md.Render([]float64{1}))
+	}
+	close(blocked.release)
+	md.Close()
+	<-md.Done()
+
+	// every frame the display didn't render was dropped
+	assert.Equal(t, uint64(5), slow.rendered.Value()+slow.dropped.Value())
+	assert.GreaterOrEqual(t, slow.dropped.Value(), uint64(3))
+}
+
+func TestLateFrames(t *testing.T) {
+	m := newPipelineMetrics(metrics.NewRegistry())
+	m.dropped(&syncRecorder{}, 2)
+	assert.Equal(t, uint64(2), m.late.Value())
+}
+
+func text(t *testing.T, reg *metrics.Registry) string {
+	var b strings.Builder
+	assert.NoError(t, reg.WriteText(&b))
+	return b.String()
+}
+
+func TestBuildDisplaysMeasured(t *testing.T) {
+	m := newPipelineMetrics(metrics.NewRegistry())
+	sock := "unix:" + filepath.Join(t.TempDir(), "eq.sock")
+	cfg := readConfig(t, "displays: [{type: web, address: 127.0.0.1:0}, {type: bands, listen: ["+sock+"]}]")
+	e := eq.New(48_000, 1024, 2)
+	live, d, err := buildDisplays(&cfg, &e, nil, nil, 0, m)
+	failIfErr(t, err)
+	defer live.closeExcept(nil)
+	failIfErr(t, d.Render([]float64{0.5, 0.5}))
+	closeDisplay(d)
+
+	out := text(t, m.reg)
+	assert.Contains(t, out, `ledeq_display_frames_rendered_total{display="0",type="web"} 1`)
+	assert.Contains(t, out, `ledeq_display_frames_rendered_total{display="1",type="bands"} 1`)
+}
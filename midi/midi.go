@@ -0,0 +1,168 @@
+// Package midi sends EQ frames as MIDI control changes, to a port or a
+// standard MIDI file.
+package midi
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+// DefaultFirstCC is the first controller used when none are given; 20 to 31
+// are undefined in the MIDI spec, so free for this.
+const DefaultFirstCC = 20
+
+// Options configures a [CC] output.
+type Options struct {
+	// Channel is the MIDI channel, 1 to 16; 0 is 1
+	Channel int
+	// Controllers is the CC number of each band. If empty, the bands use
+	// consecutive controllers from [DefaultFirstCC].
+	Controllers []int
+	// Curve is the exponent applied to values before scaling them to 0-127:
+	// above 1 favors loud bands, below 1 brings up quiet ones. 0 is linear.
+	Curve float64
+	// Threshold is how much a controller's value must change before it is
+	// sent again, so small wobbles don't flood the port. 0 sends any change.
+	Threshold int
+}
+
+// CC is a display which sends each band as a control change to w, only
+// sending controllers whose value has changed. Each frame's messages are
+// written in one call. With the default controllers, the number of bands
+// can change between frames.
+type CC struct {
+	w           io.Writer
+	status      byte
+	controllers []int
+	defaults    bool // controllers are the defaults for their bands
+	curve       float64
+	threshold   int
+
+	last []int // last value sent on each controller, -1 if none
+	buf  []byte
+}
+
+// NewCC creates an output for frames of bands values.
+func NewCC(w io.Writer, bands int, opts Options) (*CC, error) {
+	if opts.Channel == 0 {
+		opts.Channel = 1
+	}
+	if opts.Channel < 1 || opts.Channel > 16 {
+		return nil, fmt.Errorf("midi: channel must be from 1 to 16")
+	}
+	controllers := opts.Controllers
+	if len(controllers) == 0 {
+		var err error
+		if controllers, err = defaultControllers(bands); err != nil {
+			return nil, err
+		}
+	}
+	if len(controllers) != bands {
+		return nil, fmt.Errorf("midi: %d controllers given for %d bands", len(controllers), bands)
+	}
+	for _, c := range controllers {
+		if c < 0 || c > 127 {
+			return nil, fmt.Errorf("midi: controller %d is outside 0 to 127", c)
+		}
+	}
+	if opts.Curve < 0 {
+		return nil, fmt.Errorf("midi: curve must be positive")
+	}
+	if opts.Curve == 0 {
+		opts.Curve = 1
+	}
+	if opts.Threshold < 0 || opts.Threshold > 127 {
+		return nil, fmt.Errorf("midi: threshold must be from 0 to 127")
+	}
+
+	cc := &CC{
+		w:           w,
+		status:      controlChange | byte(opts.Channel-1),
+		controllers: controllers,
+		defaults:    len(opts.Controllers) == 0,
+		curve:       opts.Curve,
+		threshold:   max(opts.Threshold, 1),
+	}
+	cc.reset()
+	return cc, nil
+}
+
+// defaultControllers are consecutive controllers from [DefaultFirstCC].
+func defaultControllers(bands int) ([]int, error) {
+	if DefaultFirstCC+bands > 128 {
+		return nil, fmt.Errorf("midi: too many bands (%d) for the default controllers", bands)
+	}
+	controllers := make([]int, bands)
+	for i := range controllers {
+		controllers[i] = DefaultFirstCC + i
+	}
+	return controllers, nil
+}
+
+// reset forgets the values sent, so every controller is sent again.
+func (cc *CC) reset() {
+	cc.last = make([]int, len(cc.controllers))
+	for i := range cc.last {
+		cc.last[i] = -1
+	}
+}
+
+const controlChange = 0xb0
+
+// Value scales a band value to a controller value with the curve.
+func (cc *CC) Value(v float64) int {
+	if math.IsNaN(v) {
+		v = 0
+	}
+	v = min(max(v, 0), 1)
+	return int(math.Round(127 * math.Pow(v, cc.curve)))
+}
+
+func (cc *CC) Render(values []float64) error {
+	if len(values) != len(cc.controllers) {
+		if !cc.defaults {
+			return fmt.Errorf("midi: expected %d values but got %d", len(cc.controllers), len(values))
+		}
+		// the bins were changed while running
+		controllers, err := defaultControllers(len(values))
+		if err != nil {
+			return err
+		}
+		cc.controllers = controllers
+		cc.reset()
+	}
+	cc.buf = cc.buf[:0]
+	for i, v := range values {
+		x := cc.Value(v)
+		// small changes are skipped, except reaching either end so the
+		// controller can settle there
+		last := cc.last[i]
+		small := abs(x-last) < cc.threshold && x != 0 && x != 127
+		if last >= 0 && (x == last || small) {
+			continue
+		}
+		cc.last[i] = x
+		cc.buf = append(cc.buf, cc.status, byte(cc.controllers[i]), byte(x))
+	}
+	if len(cc.buf) == 0 {
+		return nil
+	}
+	_, err := cc.w.Write(cc.buf)
+	return err
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// Close closes the writer, if it can be.
+func (cc *CC) Close() error {
+	if c, ok := cc.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package midi
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func failIfErr(t *testing.T, err error) {
+	assert.NoError(t, err)
+	if err != nil {
+		t.FailNow()
+	}
+}
+
+func TestCC(t *testing.T) {
+	var buf bytes.Buffer
+	cc, err := NewCC(&buf, 2, Options{Channel: 3, Controllers: []int{7, 74}, Threshold: 4})
+	failIfErr(t, err)
+
+	failIfErr(t, cc.Render([]float64{0, 0.5}))
+	assert.Equal(t, []byte{0xb2, 7, 0, 0xb2, 74, 64}, buf.Bytes(), "the first frame sends everything")
+
+	buf.Reset()
+	failIfErr(t, cc.Render([]float64{0, 0.52}))
+	assert.Empty(t, buf.Bytes(), "under the threshold")
+
+	failIfErr(t, cc.Render([]float64{0.02, 2}))
+	assert.Equal(t, []byte{0xb2, 74, 127}, buf.Bytes(), "values are clipped, and the ends always sent")
+
+	buf.Reset()
+	failIfErr(t, cc.Render([]float64{0.1, 2}))
+	assert.Equal(t, []byte{0xb2, 7, 13}, buf.Bytes())
+
+	assert.Error(t, cc.Render([]float64{1}))
+}
+
+func TestCCDefaults(t *testing.T) {
+	var buf bytes.Buffer
+	cc, err := NewCC(&buf, 3, Options{Curve: 2})
+	failIfErr(t, err)
+	assert.Equal(t, 32, cc.Value(0.5))
+	assert.Equal(t, 0, cc.Value(math.NaN()))
+
+	failIfErr(t, cc.Render([]float64{1, 0.5, 0.5}))
+	failIfErr(t, cc.Render([]float64{1, 0.5, 0.51}))
+	assert.Equal(t, []byte{0xb0, 20, 127, 0xb0, 21, 32, 0xb0, 22, 32, 0xb0, 22, 33}, buf.Bytes())
+}
+
+func TestCCBandsChange(t *testing.T) {
+	var buf bytes.Buffer
+	cc, err := NewCC(&buf, 2, Options{})
+	failIfErr(t, err)
+	failIfErr(t, cc.Render([]float64{1, 1}))
+	failIfErr(t, cc.Render([]float64{1, 1, 0}))
+	assert.Equal(t, []byte{0xb0, 20, 127, 0xb0, 21, 127, 0xb0, 20, 127, 0xb0, 21, 127, 0xb0, 22, 0}, buf.Bytes())
+	assert.Error(t, cc.Render(make([]float64, 200)))
+
+	// explicit controllers can't follow
+	cc, err = NewCC(&buf, 2, Options{Controllers: []int{7, 74}})
+	failIfErr(t, err)
+	assert.ErrorContains(t, cc.Render([]float64{1, 1, 0}), "expected 2 values")
+}
+
+func TestCCOptions(t *testing.T) {
+	for _, opts := range []Options{
+		{Channel: 17},
+		{Controllers: []int{1}},
+		{Controllers: []int{1, 128}},
+		{Curve: -1},
+		{Threshold: 200},
+	} {
+		_, err := NewCC(&bytes.Buffer{}, 2, opts)
+		assert.Error(t, err, "%+v", opts)
+	}
+	_, err := NewCC(&bytes.Buffer{}, 120, Options{})
+	assert.Error(t, err, "too many bands for the default controllers")
+}
+
+func TestRecorder(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewRecorder(&buf)
+	start := time.Unix(0, 0)
+	now := start
+	r.now = func() time.Time { return now }
+
+	_, err := r.Write([]byte{0xb0, 20, 1, 21, 2}) // running status
+	failIfErr(t, err)
+	now = start.Add(200 * time.Millisecond)
+	_, err = r.Write([]byte{0xc1, 5})
+	failIfErr(t, err)
+	_, err = r.Write([]byte{0xf8})
+	assert.Error(t, err)
+	_, err = r.Write([]byte{0xb0, 20})
+	assert.Error(t, err)
+	failIfErr(t, r.Close())
+
+	track := []byte{
+		0x00, 0xb0, 20, 1,
+		0x00, 0xb0, 21, 2,
+		0x81, 0x48, 0xc1, 5, // 200 ticks
+		0x00, 0xff, 0x2f, 0x00,
+	}
+	expected := append([]byte("MThd\x00\x00\x00\x06\x00\x00\x00\x01\x01\xf4MTrk\x00\x00\x00"), byte(len(track)))
+	assert.Equal(t, append(expected, track...), buf.Bytes())
+}
+
+func TestAppendVarLen(t *testing.T) {
+	assert.Equal(t, []byte{0}, appendVarLen(nil, 0))
+	assert.Equal(t, []byte{0x7f}, appendVarLen(nil, 0x7f))
+	assert.Equal(t, []byte{0x81, 0x00}, appendVarLen(nil, 0x80))
+	assert.Equal(t, []byte{0xff, 0xff, 0xff, 0x7f}, appendVarLen(nil, 0x0fffffff))
+}
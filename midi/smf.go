@@ -0,0 +1,101 @@
+package midi
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	// ticksPerQuarter at the default 120 BPM gives 1ms ticks
+	ticksPerQuarter = 500
+	tickDuration    = time.Millisecond
+)
+
+// Recorder is a writer which records MIDI channel messages, timed by when
+// they are written, and saves them as a standard MIDI file on Close. Each
+// write must hold whole messages. Several goroutines may write at once.
+type Recorder struct {
+	mu     sync.Mutex
+	w      io.Writer
+	now    func() time.Time
+	start  time.Time
+	last   int64 // tick of the last event
+	status byte  // for running status in the input
+	track  []byte
+}
+
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{w: w, now: time.Now}
+}
+
+func (r *Recorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	if r.start.IsZero() {
+		r.start = now
+	}
+	tick := int64(now.Sub(r.start) / tickDuration)
+	for i := 0; i < len(p); {
+		status := r.status
+		if p[i]&0x80 != 0 {
+			status = p[i]
+			i++
+		}
+		if status < 0x80 || status >= 0xf0 {
+			return i, fmt.Errorf("midi: only channel messages can be recorded")
+		}
+		n := 2
+		if kind := status & 0xf0; kind == 0xc0 || kind == 0xd0 {
+			n = 1
+		}
+		if i+n > len(p) {
+			return i, fmt.Errorf("midi: incomplete message")
+		}
+		r.status = status
+		r.track = appendVarLen(r.track, uint32(tick-r.last))
+		r.track = append(r.track, status)
+		r.track = append(r.track, p[i:i+n]...)
+		r.last = tick
+		i += n
+	}
+	return len(p), nil
+}
+
+// appendVarLen appends a MIDI variable length quantity: 7 bits a byte, most
+// significant first, with the top bit set on all but the last.
+func appendVarLen(dst []byte, v uint32) []byte {
+	var b [5]byte
+	i := len(b) - 1
+	b[i] = byte(v & 0x7f)
+	for v >>= 7; v > 0; v >>= 7 {
+		i--
+		b[i] = byte(v&0x7f) | 0x80
+	}
+	return append(dst, b[i:]...)
+}
+
+// Close writes the file: a header for a single track, then the track.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	track := append(r.track, 0x00, 0xff, 0x2f, 0x00) // end of track
+	buf := []byte("MThd")
+	buf = binary.BigEndian.AppendUint32(buf, 6)
+	buf = binary.BigEndian.AppendUint16(buf, 0) // format 0
+	buf = binary.BigEndian.AppendUint16(buf, 1) // tracks
+	buf = binary.BigEndian.AppendUint16(buf, ticksPerQuarter)
+	buf = append(buf, "MTrk"...)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(track)))
+	buf = append(buf, track...)
+	if _, err := r.w.Write(buf); err != nil {
+		return err
+	}
+	if c, ok := r.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
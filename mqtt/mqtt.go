@@ -0,0 +1,203 @@
+// Package mqtt publishes EQ frames to an MQTT broker, for home automation.
+package mqtt
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/rabidaudio/led-eq/eq"
+)
+
+// Publisher sends messages to a broker. [Dial] connects one with the paho
+// client; tests can use a stand-in.
+type Publisher interface {
+	Publish(topic string, qos byte, retained bool, payload []byte) error
+	Close() error
+}
+
+// Default topics of each message.
+const (
+	DefaultBands = "led-eq/bands"
+	DefaultLevel = "led-eq/level"
+	DefaultBeat  = "led-eq/beat"
+)
+
+// DefaultRate is how many frames a second are published by default, since
+// home automation rarely needs more and brokers may throttle clients.
+const DefaultRate = 10
+
+// Options configures an [Output]. Empty topics aren't published.
+type Options struct {
+	// Bands is sent every band as a JSON array of numbers, with null for
+	// silence in dB
+	Bands string
+	// Level is sent the overall level of the frame as a number, see
+	// [eq.Level]
+	Level string
+	// Beat is sent 1 on each beat in the lowest BeatBands bands (0 is all),
+	// see [eq.BeatDetector]. Beats are never retained.
+	Beat      string
+	BeatBands int
+	// QoS is the MQTT quality of service, 0 to 2
+	QoS byte
+	// Retain keeps the last bands and level on the broker for new
+	// subscribers
+	Retain bool
+	// Rate is the most frames a second to publish, 0 for every frame.
+	// Beats are always published straight away.
+	Rate float64
+}
+
+// Output is a display which publishes frames to MQTT topics.
+type Output struct {
+	p     Publisher
+	opts  Options
+	beats *eq.BeatDetector
+	db    atomic.Bool
+
+	interval time.Duration
+	last     time.Time
+	mags     []float64
+	buf      []byte
+}
+
+// NewOutput publishes frames spaced period apart to p.
+func NewOutput(p Publisher, period time.Duration, opts Options) (*Output, error) {
+	if opts.QoS > 2 {
+		return nil, fmt.Errorf("mqtt: qos must be 0, 1 or 2")
+	}
+	if opts.Rate < 0 {
+		return nil, fmt.Errorf("mqtt: rate must be positive")
+	}
+	o := &Output{p: p, opts: opts}
+	if opts.Beat != "" {
+		o.beats = eq.NewBeatDetector(opts.BeatBands, period)
+	}
+	if opts.Rate > 0 {
+		o.interval = time.Duration(float64(time.Second) / opts.Rate)
+	}
+	return o, nil
+}
+
+// SetDB sets whether the frames are in dB, so the level and beats are
+// found from the magnitudes. It can be called while rendering.
+func (o *Output) SetDB(db bool) {
+	o.db.Store(db)
+}
+
+func (o *Output) Render(values []float64) error {
+	mags := values
+	if o.db.Load() {
+		o.mags = append(o.mags[:0], values...)
+		eq.FromDB(o.mags)
+		mags = o.mags
+	}
+	if o.beats != nil && o.beats.Detect(mags) {
+		if err := o.p.Publish(o.opts.Beat, o.opts.QoS, false, []byte("1")); err != nil {
+			return err
+		}
+	}
+	if now := time.Now(); o.interval > 0 {
+		if now.Sub(o.last) < o.interval {
+			return nil
+		}
+		o.last = now
+	}
+
+	if o.opts.Bands != "" {
+		o.buf = appendBands(o.buf[:0], values)
+		if err := o.p.Publish(o.opts.Bands, o.opts.QoS, o.opts.Retain, o.buf); err != nil {
+			return err
+		}
+	}
+	if o.opts.Level != "" {
+		o.buf = strconv.AppendFloat(o.buf[:0], eq.Level(mags), 'g', 4, 64)
+		return o.p.Publish(o.opts.Level, o.opts.QoS, o.opts.Retain, o.buf)
+	}
+	return nil
+}
+
+// appendBands writes values as a JSON array. Those JSON can't hold, such as
+// -Inf dB for silence, are written as null.
+func appendBands(b []byte, values []float64) []byte {
+	b = append(b, '[')
+	for i, v := range values {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			b = append(b, "null"...)
+		} else {
+			b = strconv.AppendFloat(b, v, 'g', -1, 64)
+		}
+	}
+	return append(b, ']')
+}
+
+// Close closes the publisher.
+func (o *Output) Close() error {
+	return o.p.Close()
+}
+
+// DialOptions are how to connect to a broker.
+type DialOptions struct {
+	// Broker is the broker's URL, such as tcp://localhost:1883 or
+	// ssl://broker:8883, or a host[:port] to connect to without TLS
+	Broker   string
+	ClientID string
+	Username string
+	Password string
+}
+
+// timeout for connecting and for each publish to be acknowledged
+const timeout = 5 * time.Second
+
+type pahoPublisher struct {
+	c paho.Client
+}
+
+// Dial connects to a broker. The connection is re-established if it drops.
+func Dial(opts DialOptions) (Publisher, error) {
+	broker := opts.Broker
+	if !strings.Contains(broker, "://") {
+		if _, _, err := net.SplitHostPort(broker); err != nil {
+			broker = net.JoinHostPort(broker, "1883")
+		}
+		broker = "tcp://" + broker
+	}
+	po := paho.NewClientOptions().
+		AddBroker(broker).
+		SetClientID(opts.ClientID).
+		SetUsername(opts.Username).
+		SetPassword(opts.Password).
+		SetConnectTimeout(timeout).
+		SetAutoReconnect(true)
+	c := paho.NewClient(po)
+	t := c.Connect()
+	if !t.WaitTimeout(timeout) {
+		return nil, fmt.Errorf("mqtt: timed out connecting to %s", broker)
+	}
+	if err := t.Error(); err != nil {
+		return nil, fmt.Errorf("mqtt: %w", err)
+	}
+	return &pahoPublisher{c}, nil
+}
+
+func (p *pahoPublisher) Publish(topic string, qos byte, retained bool, payload []byte) error {
+	t := p.c.Publish(topic, qos, retained, payload)
+	if !t.WaitTimeout(timeout) {
+		return fmt.Errorf("mqtt: timed out publishing to %s", topic)
+	}
+	return t.Error()
+}
+
+func (p *pahoPublisher) Close() error {
+	p.c.Disconnect(uint(timeout / time.Millisecond))
+	return nil
+}
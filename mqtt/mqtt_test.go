@@ -0,0 +1,194 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func failIfErr(t *testing.T, err error) {
+	assert.NoError(t, err)
+	if err != nil {
+		t.FailNow()
+	}
+}
+
+type published struct {
+	topic    string
+	qos      byte
+	retained bool
+	payload  string
+}
+
+// fakePublisher stands in for a broker
+type fakePublisher struct {
+	msgs   []published
+	err    error
+	closed bool
+}
+
+func (p *fakePublisher) Publish(topic string, qos byte, retained bool, payload []byte) error {
+	p.msgs = append(p.msgs, published{topic, qos, retained, string(payload)})
+	return p.err
+}
+
+func (p *fakePublisher) Close() error {
+	p.closed = true
+	return nil
+}
+
+func TestOutput(t *testing.T) {
+	p := &fakePublisher{}
+	o, err := NewOutput(p, 10*time.Millisecond, Options{
+		Bands: DefaultBands, Level: "home/eq/rms", QoS: 1, Retain: true,
+	})
+	failIfErr(t, err)
+
+	failIfErr(t, o.Render([]float64{0.5, 0.5}))
+	assert.Equal(t, []published{
+		{"led-eq/bands", 1, true, "[0.5,0.5]"},
+		{"home/eq/rms", 1, true, "0.5"},
+	}, p.msgs)
+
+	failIfErr(t, o.Close())
+	assert.True(t, p.closed)
+}
+
+func TestOutputSilenceInDB(t *testing.T) {
+	p := &fakePublisher{}
+	o, err := NewOutput(p, 10*time.Millisecond, Options{Bands: DefaultBands})
+	failIfErr(t, err)
+	failIfErr(t, o.Render([]float64{math.Inf(-1), -6}))
+	assert.Equal(t, []published{{"led-eq/bands", 0, false, "[null,-6]"}}, p.msgs)
+}
+
+func TestOutputLevelInDB(t *testing.T) {
+	p := &fakePublisher{}
+	o, err := NewOutput(p, 10*time.Millisecond, Options{Level: DefaultLevel, Beat: DefaultBeat})
+	failIfErr(t, err)
+	o.SetDB(true)
+
+	// silence is the quietest level, and never a beat
+	for range 10 {
+		failIfErr(t, o.Render([]float64{0}))
+	}
+	failIfErr(t, o.Render([]float64{math.Inf(-1)}))
+	assert.Equal(t, published{"led-eq/level", 0, false, "1"}, p.msgs[0])
+	assert.Equal(t, published{"led-eq/level", 0, false, "0"}, p.msgs[len(p.msgs)-1])
+	assert.Len(t, p.msgs, 11)
+}
+
+func TestOutputBeatsAndRate(t *testing.T) {
+	p := &fakePublisher{}
+	o, err := NewOutput(p, 10*time.Millisecond, Options{
+		Level: DefaultLevel, Beat: DefaultBeat, Retain: true, Rate: 1,
+	})
+	failIfErr(t, err)
+
+	// only the first frame is within the rate, but the beat gets through
+	for _, v := range []float64{0.1, 0.1, 0.1, 1} {
+		failIfErr(t, o.Render([]float64{v}))
+	}
+	assert.Equal(t, []published{
+		{"led-eq/level", 0, true, "0.1"},
+		{"led-eq/beat", 0, false, "1"},
+	}, p.msgs)
+}
+
+func TestOutputErrors(t *testing.T) {
+	p := &fakePublisher{err: errors.New("disconnected")}
+	o, err := NewOutput(p, time.Millisecond, Options{Bands: DefaultBands})
+	failIfErr(t, err)
+	assert.ErrorContains(t, o.Render([]float64{1}), "disconnected")
+
+	_, err = NewOutput(p, time.Millisecond, Options{QoS: 3})
+	assert.Error(t, err)
+	_, err = NewOutput(p, time.Millisecond, Options{Rate: -1})
+	assert.Error(t, err)
+}
+
+// serveBroker is a minimal MQTT 3.1.1 broker which accepts one client and
+// reports the QoS 0 messages it publishes.
+func serveBroker(t *testing.T) (string, <-chan published) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	failIfErr(t, err)
+	t.Cleanup(func() { l.Close() })
+	msgs := make(chan published, 16)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			header, err := r.ReadByte()
+			if err != nil {
+				return
+			}
+			n, err := readRemainingLength(r)
+			if err != nil {
+				return
+			}
+			body := make([]byte, n)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return
+			}
+			switch header >> 4 {
+			case 1: // CONNECT
+				conn.Write([]byte{0x20, 2, 0, 0})
+			case 3: // PUBLISH
+				topicLen := int(binary.BigEndian.Uint16(body))
+				msgs <- published{
+					topic:    string(body[2 : 2+topicLen]),
+					qos:      header >> 1 & 3,
+					retained: header&1 == 1,
+					payload:  string(body[2+topicLen:]),
+				}
+			case 12: // PINGREQ
+				conn.Write([]byte{0xd0, 0})
+			case 14: // DISCONNECT
+				return
+			}
+		}
+	}()
+	return l.Addr().String(), msgs
+}
+
+func readRemainingLength(r *bufio.Reader) (int, error) {
+	n, shift := 0, 0
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		n |= int(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return n, nil
+		}
+		shift += 7
+	}
+}
+
+func TestDial(t *testing.T) {
+	addr, msgs := serveBroker(t)
+	p, err := Dial(DialOptions{Broker: addr, ClientID: "test"})
+	failIfErr(t, err)
+	o, err := NewOutput(p, 10*time.Millisecond, Options{Level: DefaultLevel, Retain: true})
+	failIfErr(t, err)
+	failIfErr(t, o.Render([]float64{0.25}))
+	select {
+	case m := <-msgs:
+		assert.Equal(t, published{"led-eq/level", 0, true, "0.25"}, m)
+	case <-time.After(time.Second):
+		t.Fatal("nothing published")
+	}
+	failIfErr(t, o.Close())
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/rabidaudio/led-eq/led"
+)
+
+// runOPCServer receives Open Pixel Control frames, such as from an opc
+// display, and draws them in the terminal, for trying out LED layouts
+// without hardware.
+func runOPCServer(args []string) error {
+	fs := newFlagSet("opc-server")
+	listen := fs.String("listen", ":"+strconv.Itoa(led.OPCPort), "address to listen on")
+	channel := fs.Int("channel", 0, "only show this OPC channel, 0 for all")
+	width := fs.Int("width", 0, "pixels per row, to show a matrix (default: fit the terminal)")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return invalid(fs, "unexpected argument %q", fs.Arg(0))
+	}
+	if *channel < 0 || *channel > 255 {
+		return invalid(fs, "-channel must be from 0 to 255")
+	}
+	if *width < 0 {
+		return invalid(fs, "-width must be positive")
+	}
+
+	l, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	v := &pixelView{addr: l.Addr().String(), width: *width}
+	go led.ServeOPC(l, func(ch byte, pixels []led.Color) {
+		// channel 0 is a broadcast, so always shown
+		if *channel == 0 || ch == 0 || int(ch) == *channel {
+			v.set(pixels)
+		}
+	})
+	_, err = tea.NewProgram(v, tea.WithFPS(90)).Run()
+	return err
+}
+
+// pixelView shows the latest frame received by the OPC server.
+type pixelView struct {
+	addr  string
+	width int // pixels per row, or 0 to fit the terminal
+	cols  int // terminal width
+
+	mu     sync.Mutex
+	pixels []led.Color
+	frames int
+}
+
+type tick struct{}
+
+func (v *pixelView) set(pixels []led.Color) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.pixels = append(v.pixels[:0], pixels...)
+	v.frames++
+}
+
+func (v *pixelView) Init() tea.Cmd {
+	return v.awaitNext()
+}
+
+func (v *pixelView) awaitNext() tea.Cmd {
+	return tea.Tick(time.Second/60, func(time.Time) tea.Msg { return tick{} })
+}
+
+func (v *pixelView) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "ctrl+c":
+			return v, tea.Quit
+		}
+	case tea.WindowSizeMsg:
+		v.cols = msg.Width
+	case tick:
+		return v, v.awaitNext()
+	}
+	return v, nil
+}
+
+func (v *pixelView) View() string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	width := v.width
+	if width == 0 {
+		width = max(v.cols/2, 1)
+	}
+	return fmt.Sprintf("listening on %s, %d frames of %d pixels (q to quit)\n", v.addr, v.frames, len(v.pixels)) +
+		renderPixels(v.pixels, width)
+}
+
+// renderPixels draws each pixel as two colored blocks, width to a row.
+func renderPixels(pixels []led.Color, width int) string {
+	var b strings.Builder
+	for row := range slices.Chunk(pixels, width) {
+		for _, c := range row {
+			fmt.Fprintf(&b, "\x1b[38;2;%d;%d;%dm██", c.R, c.G, c.B)
+		}
+		b.WriteString("\x1b[0m\n")
+	}
+	return b.String()
+}
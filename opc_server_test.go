@@ -0,0 +1,17 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/rabidaudio/led-eq/led"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderPixels(t *testing.T) {
+	pixels := []led.Color{{R: 255}, {G: 128}, {B: 1}}
+	assert.Equal(t,
+		"\x1b[38;2;255;0;0m██\x1b[38;2;0;128;0m██\x1b[0m\n"+
+			"\x1b[38;2;0;0;1m██\x1b[0m\n",
+		renderPixels(pixels, 2))
+	assert.Equal(t, "", renderPixels(nil, 2))
+}
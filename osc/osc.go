@@ -0,0 +1,75 @@
+// Package osc sends EQ frames as Open Sound Control messages over UDP.
+package osc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Message is an OSC message. Args may be float32, float64 (sent as float32),
+// int32, int, string, bool or []float32, which is sent as an array.
+type Message struct {
+	Address string
+	Args    []any
+}
+
+// AppendMessage encodes m and appends it to dst.
+func AppendMessage(dst []byte, m Message) ([]byte, error) {
+	dst = appendString(dst, m.Address)
+	tags := []byte{','}
+	for _, a := range m.Args {
+		switch a := a.(type) {
+		case float32, float64:
+			tags = append(tags, 'f')
+		case int32, int:
+			tags = append(tags, 'i')
+		case string:
+			tags = append(tags, 's')
+		case bool:
+			if a {
+				tags = append(tags, 'T')
+			} else {
+				tags = append(tags, 'F')
+			}
+		case []float32:
+			tags = append(tags, '[')
+			for range a {
+				tags = append(tags, 'f')
+			}
+			tags = append(tags, ']')
+		default:
+			return dst, fmt.Errorf("osc: unsupported argument type %T", a)
+		}
+	}
+	dst = appendString(dst, string(tags))
+	for _, a := range m.Args {
+		switch a := a.(type) {
+		case float32:
+			dst = appendFloat(dst, a)
+		case float64:
+			dst = appendFloat(dst, float32(a))
+		case int32:
+			dst = binary.BigEndian.AppendUint32(dst, uint32(a))
+		case int:
+			dst = binary.BigEndian.AppendUint32(dst, uint32(int32(a)))
+		case string:
+			dst = appendString(dst, a)
+		case []float32:
+			for _, f := range a {
+				dst = appendFloat(dst, f)
+			}
+		}
+	}
+	return dst, nil
+}
+
+// appendString appends s with a null terminator, padded to 4 bytes.
+func appendString(dst []byte, s string) []byte {
+	dst = append(dst, s...)
+	return append(dst, make([]byte, 4-len(s)%4)...)
+}
+
+func appendFloat(dst []byte, f float32) []byte {
+	return binary.BigEndian.AppendUint32(dst, math.Float32bits(f))
+}
@@ -0,0 +1,127 @@
+package osc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func failIfErr(t *testing.T, err error) {
+	assert.NoError(t, err)
+	if err != nil {
+		t.FailNow()
+	}
+}
+
+func TestAppendMessage(t *testing.T) {
+	b, err := AppendMessage(nil, Message{"/eq", []any{float32(1), 2, "hi", true, []float32{0.5}}})
+	failIfErr(t, err)
+	assert.Equal(t, []byte(
+		"/eq\x00"+
+			",fisT[f]\x00\x00\x00\x00"+
+			"\x3f\x80\x00\x00"+
+			"\x00\x00\x00\x02"+
+			"hi\x00\x00"+
+			"\x3f\x00\x00\x00"), b)
+
+	_, err = AppendMessage(nil, Message{"/eq", []any{int64(1)}})
+	assert.Error(t, err)
+}
+
+// message is a decoded OSC message with float and int arguments
+type message struct {
+	address string
+	tags    string
+	args    []float64
+}
+
+func readString(b []byte) (string, []byte) {
+	n := bytes.IndexByte(b, 0)
+	return string(b[:n]), b[n+4-n%4:]
+}
+
+func decode(b []byte) message {
+	var m message
+	m.address, b = readString(b)
+	m.tags, b = readString(b)
+	for _, tag := range m.tags[1:] {
+		switch tag {
+		case 'f':
+			m.args = append(m.args, float64(math.Float32frombits(binary.BigEndian.Uint32(b))))
+			b = b[4:]
+		case 'i':
+			m.args = append(m.args, float64(int32(binary.BigEndian.Uint32(b))))
+			b = b[4:]
+		}
+	}
+	return m
+}
+
+func listen(t *testing.T) (*net.UDPConn, func() message) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	failIfErr(t, err)
+	t.Cleanup(func() { conn.Close() })
+	buf := make([]byte, 2048)
+	return conn, func() message {
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, err := conn.Read(buf)
+		failIfErr(t, err)
+		return decode(buf[:n])
+	}
+}
+
+func TestOutput(t *testing.T) {
+	conn, next := listen(t)
+	o, err := NewOutput(conn.LocalAddr().String(), 10*time.Millisecond, Options{
+		Bands: DefaultBands, Band: "/band{n}/value", Level: DefaultLevel,
+	})
+	failIfErr(t, err)
+	defer o.Close()
+
+	failIfErr(t, o.Render([]float64{0.5, 0.5}))
+	assert.Equal(t, message{"/eq/bands", ",[ff]", []float64{0.5, 0.5}}, next())
+	assert.Equal(t, message{"/band0/value", ",f", []float64{0.5}}, next())
+	assert.Equal(t, message{"/band1/value", ",f", []float64{0.5}}, next())
+	assert.Equal(t, message{"/eq/level", ",f", []float64{0.5}}, next())
+}
+
+func TestOutputLevelInDB(t *testing.T) {
+	conn, next := listen(t)
+	o, err := NewOutput(conn.LocalAddr().String(), 10*time.Millisecond, Options{Level: DefaultLevel})
+	failIfErr(t, err)
+	defer o.Close()
+	o.SetDB(true)
+
+	failIfErr(t, o.Render([]float64{math.Inf(-1), 0}))
+	assert.Equal(t, message{"/eq/level", ",f", []float64{float64(float32(math.Sqrt(0.5)))}}, next())
+	failIfErr(t, o.Render([]float64{math.Inf(-1), math.Inf(-1)}))
+	assert.Equal(t, message{"/eq/level", ",f", []float64{0}}, next())
+}
+
+func TestOutputBeatsAndRate(t *testing.T) {
+	conn, next := listen(t)
+	o, err := NewOutput(conn.LocalAddr().String(), 10*time.Millisecond, Options{
+		Level: DefaultLevel, Beat: DefaultBeat, Rate: 1,
+	})
+	failIfErr(t, err)
+	defer o.Close()
+
+	// only the first frame is within the rate, but the beat gets through
+	for _, v := range []float64{0.1, 0.1, 0.1, 1} {
+		failIfErr(t, o.Render([]float64{v}))
+	}
+	assert.Equal(t, message{"/eq/level", ",f", []float64{float64(float32(0.1))}}, next())
+	assert.Equal(t, message{"/eq/beat", ",i", []float64{1}}, next())
+}
+
+func TestOutputOptions(t *testing.T) {
+	_, err := NewOutput("127.0.0.1:9000", time.Millisecond, Options{Band: "/eq/band"})
+	assert.ErrorContains(t, err, "{n}")
+	_, err = NewOutput("127.0.0.1:9000", time.Millisecond, Options{Rate: -1})
+	assert.Error(t, err)
+}
@@ -0,0 +1,140 @@
+package osc
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/rabidaudio/led-eq/eq"
+)
+
+// Default addresses of each message.
+const (
+	DefaultBands = "/eq/bands"
+	DefaultBand  = "/eq/band/{n}"
+	DefaultLevel = "/eq/level"
+	DefaultBeat  = "/eq/beat"
+)
+
+// Options configures an [Output]. Empty addresses aren't sent.
+type Options struct {
+	// Bands is sent every band as an array of floats
+	Bands string
+	// Band is sent each band's value as a float, with {n} replaced by the
+	// band number from 0
+	Band string
+	// Level is sent the overall level of the frame, see [eq.Level]
+	Level string
+	// Beat is sent 1 on each beat in the lowest BeatBands bands (0 is all),
+	// see [eq.BeatDetector]
+	Beat      string
+	BeatBands int
+	// Rate is the most frames a second to send, 0 for every frame. Beats are
+	// always sent straight away.
+	Rate float64
+}
+
+// Output is a display which sends each frame as OSC messages over UDP,
+// one message per packet.
+type Output struct {
+	opts  Options
+	conn  net.Conn
+	beats *eq.BeatDetector
+	band  []string // address of each band
+	db    atomic.Bool
+
+	interval time.Duration
+	last     time.Time
+
+	floats []float32
+	mags   []float64
+	buf    []byte
+}
+
+// NewOutput sends to address (host:port) for frames spaced period apart.
+func NewOutput(address string, period time.Duration, opts Options) (*Output, error) {
+	if opts.Band != "" && !strings.Contains(opts.Band, "{n}") {
+		return nil, fmt.Errorf("osc: band address %q has no {n}", opts.Band)
+	}
+	if opts.Rate < 0 {
+		return nil, fmt.Errorf("osc: rate must be positive")
+	}
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	o := &Output{opts: opts, conn: conn}
+	if opts.Beat != "" {
+		o.beats = eq.NewBeatDetector(opts.BeatBands, period)
+	}
+	if opts.Rate > 0 {
+		o.interval = time.Duration(float64(time.Second) / opts.Rate)
+	}
+	return o, nil
+}
+
+// SetDB sets whether the frames are in dB, so the level and beats are
+// found from the magnitudes. It can be called while rendering.
+func (o *Output) SetDB(db bool) {
+	o.db.Store(db)
+}
+
+func (o *Output) Render(values []float64) error {
+	mags := values
+	if o.db.Load() {
+		o.mags = append(o.mags[:0], values...)
+		eq.FromDB(o.mags)
+		mags = o.mags
+	}
+	if o.beats != nil && o.beats.Detect(mags) {
+		if err := o.send(Message{o.opts.Beat, []any{int32(1)}}); err != nil {
+			return err
+		}
+	}
+	if now := time.Now(); o.interval > 0 {
+		if now.Sub(o.last) < o.interval {
+			return nil
+		}
+		o.last = now
+	}
+
+	if o.opts.Bands != "" {
+		o.floats = o.floats[:0]
+		for _, v := range values {
+			o.floats = append(o.floats, float32(v))
+		}
+		if err := o.send(Message{o.opts.Bands, []any{o.floats}}); err != nil {
+			return err
+		}
+	}
+	if o.opts.Band != "" {
+		for i := len(o.band); i < len(values); i++ {
+			o.band = append(o.band, strings.ReplaceAll(o.opts.Band, "{n}", strconv.Itoa(i)))
+		}
+		for i, v := range values {
+			if err := o.send(Message{o.band[i], []any{v}}); err != nil {
+				return err
+			}
+		}
+	}
+	if o.opts.Level != "" {
+		return o.send(Message{o.opts.Level, []any{eq.Level(mags)}})
+	}
+	return nil
+}
+
+func (o *Output) send(m Message) error {
+	var err error
+	if o.buf, err = AppendMessage(o.buf[:0], m); err != nil {
+		return err
+	}
+	_, err = o.conn.Write(o.buf)
+	return err
+}
+
+func (o *Output) Close() error {
+	return o.conn.Close()
+}
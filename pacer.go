@@ -0,0 +1,50 @@
+package main
+
+import (
+	"time"
+
+	"github.com/faiface/beep"
+)
+
+// Pacing controls how [Pace] consumes a stream when there is no audio
+// device to pull samples for us.
+type Pacing int
+
+const (
+	// RealTime consumes samples at the sample rate, as a speaker would
+	RealTime Pacing = iota
+	// AsFastAsPossible consumes samples without waiting
+	AsFastAsPossible
+)
+
+// Pace drains s in chunks of chunkSize samples in place of the speaker.
+// With [RealTime], each chunk is released when the wall clock catches up with
+// the sample position, so timing doesn't drift even if a chunk is slow.
+func Pace(s beep.Streamer, sampleRate, chunkSize int, pacing Pacing) error {
+	return pace(s, sampleRate, chunkSize, pacing, time.Now, time.Sleep)
+}
+
+func pace(s beep.Streamer, sampleRate, chunkSize int, pacing Pacing,
+	now func() time.Time, sleep func(time.Duration)) error {
+
+	buf := make([][2]float64, chunkSize)
+	start := now()
+	var pos int64 // samples consumed
+	for {
+		if pacing == RealTime {
+			due := start.Add(samplesToDuration(pos, sampleRate))
+			if wait := due.Sub(now()); wait > 0 {
+				sleep(wait)
+			}
+		}
+		n, ok := s.Stream(buf)
+		pos += int64(n)
+		if !ok {
+			return s.Err()
+		}
+	}
+}
+
+func samplesToDuration(samples int64, sampleRate int) time.Duration {
+	return time.Duration(samples) * time.Second / time.Duration(sampleRate)
+}
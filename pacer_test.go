@@ -0,0 +1,86 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/rabidaudio/led-eq/eq"
+	"github.com/stretchr/testify/assert"
+)
+
+// sine generates `length` samples of a sin wave of peak amplitude 1
+func sine(freq float64, sampleRate, length int) beep.Streamer {
+	i := 0
+	return beep.StreamerFunc(func(samples [][2]float64) (n int, ok bool) {
+		for n = range samples {
+			if i >= length {
+				return n, n > 0
+			}
+			v := math.Sin(2 * math.Pi * freq * float64(i) / float64(sampleRate))
+			samples[n] = [2]float64{v, v}
+			i++
+		}
+		return len(samples), true
+	})
+}
+
+// recordingDisplay keeps a copy of every frame it is given
+type recordingDisplay struct {
+	frames [][]float64
+}
+
+func (d *recordingDisplay) Render(values []float64) error {
+	d.frames = append(d.frames, append([]float64(nil), values...))
+	return nil
+}
+
+func TestPaceAsFastAsPossible(t *testing.T) {
+	e := eq.New(48_000, 1024, 8)
+	d := &recordingDisplay{}
+	wrap := EQStreamWrapper{Streamer: sine(440, 48_000, 48_000), eq: &e, d: d}
+
+	start := time.Now()
+	err := Pace(&wrap, 48_000, 1024, AsFastAsPossible)
+	assert.NoError(t, err)
+	assert.Less(t, time.Since(start), 1*time.Second)
+
+	assert.Len(t, d.frames, 48_000/1024)
+}
+
+func TestPaceRealTime(t *testing.T) {
+	clock := time.Unix(0, 0)
+	var slept time.Duration
+	now := func() time.Time { return clock }
+	sleep := func(d time.Duration) {
+		slept += d
+		clock = clock.Add(d)
+	}
+
+	err := pace(sine(440, 48_000, 48_000), 48_000, 4800, RealTime, now, sleep)
+	assert.NoError(t, err)
+	// the final (empty) read is due at the end of the stream
+	assert.Equal(t, 1*time.Second, slept)
+}
+
+func TestPaceRealTimeDoesNotDrift(t *testing.T) {
+	clock := time.Unix(0, 0)
+	var sleeps []time.Duration
+	now := func() time.Time { return clock }
+	sleep := func(d time.Duration) {
+		sleeps = append(sleeps, d)
+		clock = clock.Add(d)
+	}
+	slow := beep.StreamerFunc(func(samples [][2]float64) (n int, ok bool) {
+		clock = clock.Add(30 * time.Millisecond) // processing time
+		return len(samples), clock.Before(time.Unix(1, 0))
+	})
+
+	err := pace(slow, 48_000, 4800, RealTime, now, sleep)
+	assert.NoError(t, err)
+	// each chunk is 100ms, so only the remaining 70ms is waited for
+	for _, s := range sleeps {
+		assert.Equal(t, 70*time.Millisecond, s)
+	}
+}
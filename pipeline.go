@@ -0,0 +1,702 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/rabidaudio/led-eq/bandcast"
+	"github.com/rabidaudio/led-eq/config"
+	"github.com/rabidaudio/led-eq/eq"
+	"github.com/rabidaudio/led-eq/led"
+	"github.com/rabidaudio/led-eq/metrics"
+	"github.com/rabidaudio/led-eq/midi"
+	"github.com/rabidaudio/led-eq/mqtt"
+	"github.com/rabidaudio/led-eq/osc"
+	"github.com/rabidaudio/led-eq/resample"
+	"github.com/rabidaudio/led-eq/wav"
+	"github.com/rabidaudio/led-eq/web"
+)
+
+// how often to check the config file for changes
+const reloadInterval = 500 * time.Millisecond
+
+// how long to wait for displays to finish closing, in case one is stuck
+const closeTimeout = 2 * time.Second
+
+// where web displays serve by default
+const defaultWebAddress = ":8080"
+
+// how many frames each display can fall behind when there are several
+const displayQueueLen = 4
+
+// openInput opens the configured wav file (or stdin) and resamples it to
+// the analysis rate, if one is set. closer closes the file once the stream
+// is finished with.
+func openInput(cfg *config.Config) (src beep.Streamer, sampleRate int, closer io.Closer, err error) {
+	var wv *wav.WavReader
+	if path := cfg.Input.Path; path != "" && path != "-" {
+		wf, err := wav.OpenWavFile(path)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		wv, closer = wf.WavReader, wf
+	} else {
+		wv, err = wav.OpenWav(os.Stdin)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		closer = io.NopCloser(os.Stdin)
+	}
+	src, sampleRate = wv, wv.SampleRate()
+	if rate := cfg.EQ.SampleRate; rate != 0 && sampleRate != rate {
+		src = resample.New(resample.DefaultQuality, sampleRate, rate, wv)
+		sampleRate = rate
+	}
+	return src, sampleRate, closer, nil
+}
+
+// liveDisplays are the displays which keep running across reloads rather
+// than being rebuilt: the terminal display can't be started once the
+// pipeline is running, web servers and bandcast senders keep their ports
+// and clients, and MIDI recordings would start over. Displays which find
+// the level of frames are also kept here, to be told when they switch to dB.
+type liveDisplays struct {
+	terminal   *TerminalDisplay
+	web        map[string]*web.Server      // by address
+	bands      map[string]*bandcast.Sender // by bandsKey
+	recordings map[string]*midi.Recorder   // by file
+	levels     []levelDisplay
+}
+
+// levelDisplay finds the level or beats of frames, which it needs as
+// magnitudes rather than dB.
+type levelDisplay interface {
+	SetDB(db bool)
+}
+
+// closeExcept closes the servers which aren't kept in next.
+func (l *liveDisplays) closeExcept(next *liveDisplays) {
+	for addr, s := range l.web {
+		if next == nil || next.web[addr] != s {
+			if err := s.Close(); err != nil {
+				warnf("%v", err)
+			}
+		}
+	}
+	for key, s := range l.bands {
+		if next == nil || next.bands[key] != s {
+			if err := s.Close(); err != nil {
+				warnf("%v", err)
+			}
+		}
+	}
+	for file, r := range l.recordings {
+		if next == nil || next.recordings[file] != r {
+			if err := r.Close(); err != nil {
+				warnf("%s: %v", file, err)
+			}
+		}
+	}
+}
+
+// bandsKey identifies a bandcast sender by where it sends and listens.
+func bandsKey(spec *config.Display) string {
+	return spec.Address + " " + strings.Join(spec.Listen, ",")
+}
+
+// bandsDisplay sends frames of its own bins through a sender which may be
+// shared with the displays from before a reload.
+type bandsDisplay struct {
+	s    *bandcast.Sender
+	bins eq.Bins
+}
+
+func (b bandsDisplay) Render(values []float64) error {
+	return b.s.Send(b.bins, values)
+}
+
+// keepOpen hides Close from the display wrappers, for displays which
+// outlive them.
+type keepOpen struct {
+	Display
+}
+
+// buildDisplays creates the configured outputs. Rendering happens off the
+// audio goroutine, concurrently if there are several displays. The terminal
+// display and servers are returned separately: the terminal display has to
+// run on the main goroutine, and they are reused when reloading, so the
+// displays already running are passed in. Web servers and the terminal
+// display change the settings through lc, or can't if it is nil. latency is
+// the estimated delay of the audio output, used for an auto delay. Each
+// display is measured by m, if set.
+func buildDisplays(cfg *config.Config, e *eq.EQ, running *liveDisplays, lc *liveConfig, latency time.Duration,
+	m *pipelineMetrics) (_ *liveDisplays, d Display, err error) {
+	// failures return nil, so clean up what was built through its own
+	// variable
+	built := &liveDisplays{
+		web:        make(map[string]*web.Server),
+		bands:      make(map[string]*bandcast.Sender),
+		recordings: make(map[string]*midi.Recorder),
+	}
+	// a nil *liveConfig would make non-nil interfaces
+	var tuner Tuner
+	var ctrl web.Controller
+	if lc != nil {
+		tuner, ctrl = lc, lc
+	}
+	var ds []Display
+	defer func() {
+		if err != nil {
+			closeAll(ds)
+			built.closeExcept(running)
+		}
+	}()
+	for i, spec := range cfg.Displays {
+		var out Display
+		switch spec.Type {
+		case "terminal":
+			if running != nil && running.terminal == nil {
+				return nil, nil, fmt.Errorf("the terminal display can't be added without a restart")
+			}
+			if running != nil {
+				built.terminal = running.terminal
+			} else {
+				built.terminal = NewTerminalDisplay(cfg.EQ, e, tuner)
+			}
+			out = built.terminal
+		case "adalight", "tpm2":
+			sd, err := openSerial(&spec)
+			if err != nil {
+				return nil, nil, err
+			}
+			out = sd
+		case "e131", "artnet":
+			nd, err := openDMX(&spec)
+			if err != nil {
+				return nil, nil, err
+			}
+			out = nd
+		case "wled", "ddp":
+			wd, err := openWLED(&spec)
+			if err != nil {
+				return nil, nil, err
+			}
+			out = wd
+		case "opc":
+			od, err := openOPC(&spec)
+			if err != nil {
+				return nil, nil, err
+			}
+			out = od
+		case "bands":
+			key := bandsKey(&spec)
+			bs := running.bandsSender(key)
+			if bs == nil {
+				if bs, err = openBandcast(&spec, e.OutBins); err != nil {
+					return nil, nil, err
+				}
+			}
+			built.bands[key] = bs
+			out = bandsDisplay{bs, e.OutBins}
+		case "osc":
+			od, err := osc.NewOutput(spec.Address, framePeriod(e), spec.OSC.Options())
+			if err != nil {
+				return nil, nil, err
+			}
+			od.SetDB(e.OutputDB)
+			built.levels = append(built.levels, od)
+			out = od
+		case "midi":
+			var rec io.Writer
+			if spec.Device == "" {
+				file := spec.MIDI.File
+				r := running.recording(file)
+				if r == nil {
+					if r, err = recordMIDI(file); err != nil {
+						return nil, nil, err
+					}
+				}
+				built.recordings[file] = r
+				// the recording is closed with the live displays
+				rec = struct{ io.Writer }{r}
+			}
+			md, err := openMIDI(&spec, e.OutBins.Len(), rec)
+			if err != nil {
+				return nil, nil, err
+			}
+			out = md
+		case "mqtt":
+			md, err := openMQTT(&spec, framePeriod(e))
+			if err != nil {
+				return nil, nil, err
+			}
+			md.SetDB(e.OutputDB)
+			built.levels = append(built.levels, md)
+			out = md
+		case "web":
+			addr := spec.Address
+			if addr == "" {
+				addr = defaultWebAddress
+			}
+			ws := running.webServer(addr)
+			if ws == nil {
+				if ws, err = web.NewServer(addr, e.OutBins, ctrl); err != nil {
+					return nil, nil, err
+				}
+			}
+			built.web[addr] = ws
+			out = keepOpen{ws}
+		}
+		ds = append(ds, m.measure(i, spec.Type, out))
+	}
+	if len(ds) == 0 {
+		return built, nil, nil
+	}
+	// a lone display still goes through a MultiDisplay so that its errors
+	// follow the policy, but only ever renders the latest frame
+	queueLen := displayQueueLen
+	if len(ds) == 1 {
+		queueLen = 1
+	}
+	md := NewMultiDisplay(errorPolicies[cfg.OnDisplayError], queueLen, ds...)
+	if m != nil {
+		md.OnDrop = m.dropped
+	}
+	d = md
+	if delay := cfg.Delay.Resolve(latency); delay > 0 {
+		dd := NewDelayedDisplay(d, delay)
+		if m != nil {
+			dd.OnDrop = m.dropped
+		}
+		d = dd
+	}
+	return built, d, nil
+}
+
+// webServer is the running server on addr, if any.
+func (l *liveDisplays) webServer(addr string) *web.Server {
+	if l == nil {
+		return nil
+	}
+	return l.web[addr]
+}
+
+// bandsSender is the running sender for key, if any.
+func (l *liveDisplays) bandsSender(key string) *bandcast.Sender {
+	if l == nil {
+		return nil
+	}
+	return l.bands[key]
+}
+
+// recording is the running recording to file, if any.
+func (l *liveDisplays) recording(file string) *midi.Recorder {
+	if l == nil {
+		return nil
+	}
+	return l.recordings[file]
+}
+
+func openSerial(spec *config.Display) (*led.Serial, error) {
+	m, err := buildMapper(spec)
+	if err != nil {
+		return nil, err
+	}
+	proto, err := led.ParseProtocol(spec.Type)
+	if err != nil {
+		return nil, err
+	}
+	w, err := led.OpenSerial(spec.Device, spec.Baud)
+	if err != nil {
+		return nil, err
+	}
+	s, err := led.NewSerial(w, proto, m)
+	if err != nil {
+		w.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func openDMX(spec *config.Display) (*led.DMX, error) {
+	m, err := buildMapper(spec)
+	if err != nil {
+		return nil, err
+	}
+	proto, err := led.ParseDMXProtocol(spec.Type)
+	if err != nil {
+		return nil, err
+	}
+	return led.NewDMX(proto, m, led.DMXOptions{
+		Address:       spec.Address,
+		StartUniverse: spec.Universe,
+		StartChannel:  spec.Channel,
+		UniverseSize:  spec.UniverseSize,
+		Priority:      spec.Priority,
+		SourceName:    spec.SourceName,
+	})
+}
+
+func openWLED(spec *config.Display) (*led.WLED, error) {
+	m, err := buildMapper(spec)
+	if err != nil {
+		return nil, err
+	}
+	proto := led.DDP
+	if spec.Type == "wled" {
+		name := spec.Protocol
+		if name == "" {
+			name = "dnrgb" // fits any strip
+		}
+		if proto, err = led.ParseWLEDProtocol(name); err != nil {
+			return nil, err
+		}
+	}
+	return led.NewWLED(proto, m, led.WLEDOptions{Address: spec.Address, Timeout: spec.Timeout})
+}
+
+func openOPC(spec *config.Display) (*led.OPC, error) {
+	m, err := buildMapper(spec)
+	if err != nil {
+		return nil, err
+	}
+	address := spec.Address
+	if address == "" {
+		address = "localhost"
+	}
+	return led.NewOPC(address, spec.Channel, m)
+}
+
+func openBandcast(spec *config.Display, bins eq.Bins) (*bandcast.Sender, error) {
+	s, err := bandcast.NewSender(bins, spec.Address)
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range spec.Listen {
+		l, err := bandcast.Listen(addr)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		s.Serve(l)
+	}
+	return s, nil
+}
+
+// openMIDI opens the port, or writes to rec when recording to a file.
+func openMIDI(spec *config.Display, bands int, rec io.Writer) (*midi.CC, error) {
+	w := rec
+	if spec.Device != "" {
+		f, err := os.OpenFile(spec.Device, os.O_WRONLY, 0)
+		if err != nil {
+			return nil, err
+		}
+		w = f
+	}
+	cc, err := midi.NewCC(w, bands, spec.MIDI.Options())
+	if err != nil {
+		if c, ok := w.(io.Closer); ok {
+			c.Close()
+		}
+		return nil, err
+	}
+	return cc, nil
+}
+
+// recordMIDI creates the file to record to.
+func recordMIDI(path string) (*midi.Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return midi.NewRecorder(f), nil
+}
+
+func openMQTT(spec *config.Display, period time.Duration) (*mqtt.Output, error) {
+	p, err := mqtt.Dial(spec.MQTT.DialOptions(spec.Address))
+	if err != nil {
+		return nil, err
+	}
+	o, err := mqtt.NewOutput(p, period, spec.MQTT.Options())
+	if err != nil {
+		p.Close()
+		return nil, err
+	}
+	return o, nil
+}
+
+// buildMapper creates the LED mapper for an output, warning when its power
+// limit kicks in.
+func buildMapper(spec *config.Display) (*led.Mapper, error) {
+	m, err := spec.LED.Build()
+	if err != nil {
+		return nil, err
+	}
+	if m.Power != nil {
+		m.Power.OnChange = func(active bool, amps float64) {
+			if active {
+				warnf("%s: limiting power, frame wanted %.1f A of %.1f A", spec.Type, amps, m.Power.Amps)
+			} else {
+				warnf("%s: power back within budget", spec.Type)
+			}
+		}
+	}
+	return m, nil
+}
+
+// closeAll closes the displays which hold resources, when building the rest
+// failed.
+func closeAll(ds []Display) {
+	for _, d := range ds {
+		if c, ok := d.(io.Closer); ok {
+			c.Close()
+		}
+	}
+}
+
+// closeDisplay stops a display built by buildDisplays and waits for it to
+// finish, reporting any errors it collected and the frames it dropped.
+func closeDisplay(d Display) {
+	if c, ok := d.(interface {
+		Close()
+		Err() error
+	}); ok {
+		c.Close()
+		if w, ok := d.(waiter); ok {
+			select {
+			case <-w.Done():
+			case <-time.After(closeTimeout):
+				warnf("displays did not finish closing")
+			}
+		}
+		if err := c.Err(); err != nil {
+			warnf("%v", err)
+		}
+	}
+	if s, ok := d.(interface{ Stats() []DisplayStats }); ok {
+		for i, st := range s.Stats() {
+			if st.Dropped > 0 {
+				warnf("display %d fell behind: dropped %d of %d frames", i, st.Dropped, st.Dropped+st.Rendered)
+			}
+		}
+	}
+}
+
+// displaysUnchanged reports whether the displays built for a and ae would be
+// built the same for b and be.
+func displaysUnchanged(a *config.Config, ae *eq.EQ, b *config.Config, be *eq.EQ) bool {
+	return reflect.DeepEqual(a.Displays, b.Displays) && a.OnDisplayError == b.OnDisplayError &&
+		a.Delay == b.Delay && slices.Equal(ae.OutBins, be.OutBins) && framePeriod(ae) == framePeriod(be)
+}
+
+func framePeriod(e *eq.EQ) time.Duration {
+	return time.Duration(e.N) * time.Second / time.Duration(e.SampleRate)
+}
+
+func warnf(format string, a ...any) {
+	fmt.Fprintf(os.Stderr, "warning: "+format+"\n", a...)
+}
+
+// liveConfig is the configuration of a running pipeline. Changes from the
+// config file and from web displays are applied one at a time.
+type liveConfig struct {
+	mu  sync.Mutex
+	cfg config.Config
+	eq  eq.EQ
+	// apply switches the pipeline to c, returning its EQ; nil once stopped
+	apply func(c config.Config) (eq.EQ, error)
+}
+
+var _ web.Controller = (*liveConfig)(nil)
+var _ Tuner = (*liveConfig)(nil)
+
+func (lc *liveConfig) reload(c config.Config) error {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	return lc.reloadLocked(c)
+}
+
+func (lc *liveConfig) reloadLocked(c config.Config) error {
+	if lc.apply == nil {
+		return errors.New("the pipeline has stopped")
+	}
+	if c.Input != lc.cfg.Input || c.EQ.SampleRate != lc.cfg.EQ.SampleRate || c.Metrics != lc.cfg.Metrics {
+		warnf("changes to the input, sample rate and metrics address need a restart")
+	}
+	e, err := lc.apply(c)
+	if err != nil {
+		return err
+	}
+	lc.cfg, lc.eq = c, e
+	return nil
+}
+
+func (lc *liveConfig) Settings() web.Settings {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	return web.Settings{Bins: lc.eq.OutBins, Gain: lc.eq.Normalize, DB: lc.eq.OutputDB}
+}
+
+func (lc *liveConfig) Update(u web.Update) error {
+	return lc.Tune(func(c *config.EQ) {
+		if u.Bins != nil {
+			c.Bins = config.Bins{Edges: u.Bins}
+		}
+		if u.Gain != nil {
+			c.Normalize = *u.Gain
+		}
+		if u.DB != nil {
+			c.OutputDB = *u.DB
+		}
+	})
+}
+
+func (lc *liveConfig) Tune(change func(c *config.EQ)) error {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	c := lc.cfg
+	c.EQ.Bins.Edges = slices.Clone(c.EQ.Bins.Edges)
+	change(&c.EQ)
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	return lc.reloadLocked(c)
+}
+
+// runPipeline streams the input through the EQ to the displays, with play
+// consuming the stream. latency, if set, estimates how long play takes to
+// output audio for the EQ it is started with. If configPath is set, changes
+// to the file are applied while running.
+func runPipeline(cfg config.Config, configPath string, play func(s beep.Streamer, e *eq.EQ) error,
+	latency func(e *eq.EQ) time.Duration) error {
+	src, sampleRate, input, err := openInput(&cfg)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+	e, err := cfg.EQ.Build(sampleRate)
+	if err != nil {
+		return err
+	}
+
+	var outputLatency time.Duration
+	if latency != nil {
+		// fixed once the output is started
+		outputLatency = latency(&e)
+	}
+	var m *pipelineMetrics
+	if cfg.Metrics != "" {
+		reg := metrics.NewRegistry()
+		ms, err := metrics.NewServer(cfg.Metrics, reg)
+		if err != nil {
+			return err
+		}
+		defer ms.Close()
+		m = newPipelineMetrics(reg)
+	}
+
+	lc := &liveConfig{cfg: cfg, eq: e}
+	// web displays can't change the settings until apply is set
+	lc.mu.Lock()
+	live, d, err := buildDisplays(&cfg, &e, nil, lc, outputLatency, m)
+	if err != nil {
+		lc.mu.Unlock()
+		return err
+	}
+	defer func() {
+		lc.mu.Lock() // waits for any reload to finish
+		defer lc.mu.Unlock()
+		lc.apply = nil
+		closeDisplay(d)
+		live.closeExcept(nil)
+	}()
+	wrap := EQStreamWrapper{Streamer: src, eq: &e, post: cfg.BuildPost(framePeriod(&e)), d: d, metrics: m}
+	m.watchGain(&wrap)
+
+	// the settings the displays were built for
+	dcfg, de := cfg, e
+	// called with lc.mu held, which also guards d, live, dcfg and de
+	lc.apply = func(c config.Config) (eq.EQ, error) {
+		ne, err := c.EQ.Build(sampleRate)
+		if err != nil {
+			return ne, err
+		}
+		// changes which don't affect the displays keep them open, so
+		// serial ports aren't reset and network outputs don't reconnect
+		rebuild := !displaysUnchanged(&dcfg, &de, &c, &ne)
+		nl, nd := live, d
+		if rebuild {
+			if nl, nd, err = buildDisplays(&c, &ne, live, lc, outputLatency, m); err != nil {
+				return ne, err
+			}
+		}
+		if err := wrap.Reconfigure(ne, c.BuildPost(framePeriod(&ne)), nd); err != nil {
+			if rebuild {
+				closeDisplay(nd)
+				nl.closeExcept(live)
+			}
+			return ne, err
+		}
+		if rebuild {
+			// the old displays may still get a frame before the change is
+			// picked up, which is ignored once closed
+			closeDisplay(d)
+			live.closeExcept(nl)
+			for _, ws := range nl.web {
+				ws.SetBins(ne.OutBins)
+			}
+			dcfg, de = c, ne
+		}
+		if nl.terminal != nil {
+			nl.terminal.SetEQ(c.EQ, ne)
+		}
+		for _, ld := range nl.levels {
+			ld.SetDB(ne.OutputDB)
+		}
+		d, live = nd, nl
+		return ne, nil
+	}
+	lc.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if configPath != "" {
+		go config.Watch(ctx, configPath, reloadInterval, func(c config.Config, err error) {
+			if err == nil {
+				err = lc.reload(c)
+			}
+			if err != nil {
+				warnf("config not reloaded: %v", err)
+			}
+		})
+	}
+
+	td := live.terminal
+	done := make(chan error, 1)
+	go func() {
+		done <- play(&wrap, &e)
+		if td != nil {
+			td.Done()
+		}
+	}()
+
+	if td == nil {
+		return <-done
+	}
+	td.Run()
+	// quitting the display exits even if the stream isn't finished
+	select {
+	case err = <-done:
+	default:
+	}
+	return err
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rabidaudio/led-eq/bandcast"
+	"github.com/rabidaudio/led-eq/config"
+	"github.com/rabidaudio/led-eq/eq"
+	"github.com/rabidaudio/led-eq/osc"
+	"github.com/stretchr/testify/assert"
+)
+
+func failIfErr(t *testing.T, err error) {
+	assert.NoError(t, err)
+	if err != nil {
+		t.FailNow()
+	}
+}
+
+func readConfig(t *testing.T, yaml string) config.Config {
+	t.Helper()
+	cfg, err := config.Read(strings.NewReader(yaml))
+	failIfErr(t, err)
+	return cfg
+}
+
+// a web server is started, then the serial device is missing
+const failingDisplays = `
+displays:
+  - type: web
+    address: 127.0.0.1:0
+  - type: adalight
+    device: /nonexistent/ttyUSB0
+    led: {pixels: 8}
+`
+
+func TestBuildDisplaysFailure(t *testing.T) {
+	cfg := readConfig(t, failingDisplays)
+	e := eq.New(48_000, 1024, 8)
+
+	live, d, err := buildDisplays(&cfg, &e, nil, nil, 0, nil)
+	assert.Error(t, err)
+	assert.Nil(t, live)
+	assert.Nil(t, d)
+}
+
+func TestBuildDisplaysFailureOnReload(t *testing.T) {
+	web := readConfig(t, "displays: [{type: web, address: 127.0.0.1:0}]")
+	e := eq.New(48_000, 1024, 8)
+	running, d, err := buildDisplays(&web, &e, nil, nil, 0, nil)
+	failIfErr(t, err)
+	defer closeDisplay(d)
+	defer running.closeExcept(nil)
+
+	cfg := readConfig(t, failingDisplays)
+	_, _, err = buildDisplays(&cfg, &e, running, nil, 0, nil)
+	assert.Error(t, err)
+
+	// the running server is left alone
+	ws := running.web["127.0.0.1:0"]
+	conn, err := net.Dial("tcp", ws.Addr().String())
+	if assert.NoError(t, err) {
+		conn.Close()
+	}
+}
+
+func TestSingleDisplayFollowsErrorPolicy(t *testing.T) {
+	cfg := readConfig(t, "displays: [{type: web, address: 127.0.0.1:0}]\non_display_error: disable")
+	e := eq.New(48_000, 1024, 8)
+	live, d, err := buildDisplays(&cfg, &e, nil, nil, 0, nil)
+	failIfErr(t, err)
+	defer live.closeExcept(nil)
+	defer closeDisplay(d)
+
+	if assert.IsType(t, &MultiDisplay{}, d) {
+		assert.Equal(t, DisableOnError, d.(*MultiDisplay).policy)
+	}
+}
+
+func TestBandcastKeptOnReload(t *testing.T) {
+	sock := "unix:" + filepath.Join(t.TempDir(), "eq.sock")
+	cfg := readConfig(t, "displays: [{type: bands, listen: ["+sock+"]}]")
+	e := eq.New(48_000, 1024, 8)
+	running, d, err := buildDisplays(&cfg, &e, nil, nil, 0, nil)
+	failIfErr(t, err)
+	defer closeDisplay(d)
+	defer running.closeExcept(nil)
+
+	c, err := bandcast.Dial(sock)
+	failIfErr(t, err)
+	defer c.Close()
+
+	ne := eq.New(48_000, 1024, 4)
+	next, nd, err := buildDisplays(&cfg, &ne, running, nil, 0, nil)
+	failIfErr(t, err)
+	defer closeDisplay(nd)
+	running.closeExcept(next)
+	assert.Same(t, running.bands[bandsKey(&cfg.Displays[0])], next.bands[bandsKey(&cfg.Displays[0])])
+
+	failIfErr(t, nd.Render([]float64{0.5, 0.5, 0.5, 0.5}))
+	f, err := c.Next()
+	failIfErr(t, err)
+	assert.Equal(t, []float64{0.5, 0.5, 0.5, 0.5}, f.Values, "the client is still connected")
+}
+
+func TestMIDIRecordingKeptOnReload(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "eq.mid")
+	cfg := readConfig(t, "displays: [{type: midi, midi: {file: "+file+"}}]")
+	e := eq.New(48_000, 1024, 2)
+	running, d, err := buildDisplays(&cfg, &e, nil, nil, 0, nil)
+	failIfErr(t, err)
+	failIfErr(t, d.Render([]float64{0.5, 0.5}))
+
+	ne := eq.New(48_000, 1024, 3)
+	next, nd, err := buildDisplays(&cfg, &ne, running, nil, 0, nil)
+	failIfErr(t, err)
+	closeDisplay(d)
+	running.closeExcept(next)
+	failIfErr(t, nd.Render([]float64{1, 1, 1}))
+	closeDisplay(nd)
+	next.closeExcept(nil)
+
+	b, err := os.ReadFile(file)
+	failIfErr(t, err)
+	assert.True(t, bytes.HasPrefix(b, []byte("MThd")))
+	// the track holds both frames: 2 then 3 control changes
+	track := b[bytes.Index(b, []byte("MTrk"))+8:]
+	assert.Equal(t, 5, bytes.Count(track, []byte{0xb0}))
+}
+
+func TestDisplaysUnchanged(t *testing.T) {
+	cfg := readConfig(t, "displays: [{type: web, address: 127.0.0.1:0}]")
+	e := eq.New(48_000, 1024, 8)
+
+	tuned := cfg
+	tuned.EQ.Normalize *= 2
+	tuned.EQ.OutputDB = true
+	assert.True(t, displaysUnchanged(&cfg, &e, &tuned, &e), "gain and dB")
+
+	ne := eq.New(48_000, 1024, 4)
+	assert.False(t, displaysUnchanged(&cfg, &e, &cfg, &ne), "bins")
+	ne = eq.New(48_000, 2048, 8)
+	assert.False(t, displaysUnchanged(&cfg, &e, &cfg, &ne), "frame period")
+
+	moved := readConfig(t, "displays: [{type: web, address: 127.0.0.1:8081}]")
+	assert.False(t, displaysUnchanged(&cfg, &e, &moved, &e), "displays")
+	moved = cfg
+	moved.OnDisplayError = "abort"
+	assert.False(t, displaysUnchanged(&cfg, &e, &moved, &e), "error policy")
+}
+
+func TestLevelDisplaysToldOfDB(t *testing.T) {
+	cfg := readConfig(t, "displays: [{type: osc, address: 127.0.0.1:9}, {type: web, address: 127.0.0.1:0}]")
+	e := eq.New(48_000, 1024, 8)
+	live, d, err := buildDisplays(&cfg, &e, nil, nil, 0, nil)
+	failIfErr(t, err)
+	defer live.closeExcept(nil)
+	defer closeDisplay(d)
+
+	if assert.Len(t, live.levels, 1) {
+		assert.IsType(t, &osc.Output{}, live.levels[0])
+	}
+}
+
+func TestOpenInputCloses(t *testing.T) {
+	cfg := readConfig(t, "input: {path: eq/testdata/440sin_1.wav}\neq: {sample_rate: 24000}")
+	src, rate, input, err := openInput(&cfg)
+	failIfErr(t, err)
+	assert.Equal(t, 24_000, rate)
+	assert.NotNil(t, src)
+	failIfErr(t, input.Close())
+	assert.ErrorIs(t, input.Close(), os.ErrClosed, "the file was closed")
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/rabidaudio/led-eq/config"
+	"github.com/rabidaudio/led-eq/eq"
+	"github.com/rabidaudio/led-eq/frames"
+)
+
+// runReplay plays a file written by export into the terminal display, or
+// the displays of a config, at its recorded timing, for checking mappings
+// without an audio source.
+func runReplay(args []string) error {
+	fs := newFlagSet("replay")
+	speed := fs.Float64("speed", 1, "playback speed, 1 is real time")
+	start := fs.Duration("start", 0, "position to start from")
+	formatName := fs.String("format", "", "csv, ndjson or bin (default: from the file extension)")
+	configPath := fs.String("config", "", "play to the displays of a yaml config file; only its displays and db setting are used")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return invalid(fs, "a frames file is required")
+	}
+	if *speed <= 0 {
+		return invalid(fs, "-speed must be positive")
+	}
+	var format frames.Format
+	var err error
+	if *formatName != "" {
+		format, err = frames.ParseFormat(*formatName)
+	} else {
+		format, err = frames.FormatForPath(fs.Arg(0))
+	}
+	if err != nil {
+		return invalid(fs, "%v", err)
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r, err := frames.NewReader(f, format)
+	if err != nil {
+		return err
+	}
+	fr, err := frames.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	cfg := config.Config{Displays: []config.Display{{Type: "terminal"}}, OnDisplayError: "drop"}
+	if *configPath != "" {
+		if cfg, err = config.Load(*configPath); err != nil {
+			return err
+		}
+	}
+	h := r.Header()
+	cfg.EQ.Bins = config.Bins{Edges: h.Bins}
+	// the frames don't say whether they are in dB, so that is left to the
+	// config
+	e := eq.EQ{SampleRate: h.SampleRate, N: h.N, OutBins: h.Bins, OutputDB: cfg.EQ.OutputDB}
+	// the frames are shown as recorded, so there are no settings to tune
+	live, d, err := buildDisplays(&cfg, &e, nil, nil, 0, nil)
+	if err != nil {
+		return err
+	}
+	defer live.closeExcept(nil)
+	defer closeDisplay(d)
+	p := frames.NewPlayer(h, fr, d)
+	p.SetSpeed(*speed)
+	p.Seek(*start)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	td := live.terminal
+	if td != nil {
+		td.SetTransport(p)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- p.Play(ctx)
+		if td != nil {
+			td.Done()
+		}
+	}()
+	if td == nil {
+		return <-done
+	}
+	td.Run()
+	select {
+	case err = <-done:
+	default:
+	}
+	return err
+}
@@ -0,0 +1,212 @@
+package resample
+
+import (
+	"math"
+
+	"github.com/faiface/beep"
+)
+
+// DefaultQuality is the filter half-width (in taps) used by the pipeline.
+// 32 gives > 90dB stopband attenuation, which is plenty for analysis.
+const DefaultQuality = 32
+
+// maxPhases is the largest polyphase table we precompute. Odd rate pairs
+// (e.g. 44100 -> 47999) reduce to huge ratios; those compute the kernel
+// for each output sample instead.
+const maxPhases = 4096
+
+// kaiserBeta controls the window shape. ~8.6 trades a slightly wider
+// transition band for very low sidelobes.
+const kaiserBeta = 8.6
+
+// Resampler converts a stereo stream from one sample rate to another using a
+// Kaiser-windowed sinc filter, evaluated as a polyphase filter bank.
+// When downsampling, the cutoff is lowered to the new Nyquist frequency so
+// content above it is filtered out instead of aliasing into the audible band.
+type Resampler struct {
+	s beep.Streamer
+
+	from, to int
+	up, down int // to/from reduced to lowest terms
+	half     int // filter half-width in input samples
+	cutoff   float64
+
+	filters [][]float64 // [phase][2*half], nil if computed per-sample
+	kernel  []float64   // scratch for per-sample kernels
+
+	buf   [][2]float64 // input window, buf[0] is input sample `base`
+	base  int
+	in    int // number of input samples read from s
+	chunk [][2]float64
+
+	i, p int // current output position: input index i + p/up
+	eof  bool
+	err  error
+}
+
+var _ beep.Streamer = (*Resampler)(nil)
+
+// New returns a [Resampler] which converts s from sample rate `from` to `to`.
+// quality is the filter half-width in taps; see [DefaultQuality].
+func New(quality, from, to int, s beep.Streamer) *Resampler {
+	if quality < 1 {
+		panic("resample: quality must be at least 1")
+	}
+	if from <= 0 || to <= 0 {
+		panic("resample: sample rates must be positive")
+	}
+	g := gcd(from, to)
+	r := &Resampler{
+		s:      s,
+		from:   from,
+		to:     to,
+		up:     to / g,
+		down:   from / g,
+		cutoff: 1,
+		half:   quality,
+	}
+	if r.down > r.up {
+		// downsampling: widen the kernel so the lower cutoff keeps the same
+		// number of zero crossings
+		r.cutoff = float64(r.up) / float64(r.down)
+		r.half = int(math.Ceil(float64(quality) / r.cutoff))
+	}
+	if r.up <= maxPhases {
+		r.filters = make([][]float64, r.up)
+		for p := range r.filters {
+			r.filters[p] = make([]float64, 2*r.half)
+			r.fillKernel(p, r.filters[p])
+		}
+	} else {
+		r.kernel = make([]float64, 2*r.half)
+	}
+
+	// history before the first sample is silence
+	r.buf = make([][2]float64, r.half-1, 4*r.half)
+	r.base = -(r.half - 1)
+	r.chunk = make([][2]float64, 512)
+	return r
+}
+
+// SampleRate is the output sample rate.
+func (r *Resampler) SampleRate() int {
+	return r.to
+}
+
+// fillKernel computes the filter taps for phase p (an output position p/up
+// of the way between two input samples). Tap j applies to input sample
+// i-half+1+j.
+func (r *Resampler) fillKernel(p int, out []float64) {
+	frac := float64(p) / float64(r.up)
+	var sum float64
+	for j := range out {
+		d := float64(j-r.half+1) - frac
+		out[j] = r.cutoff * sinc(r.cutoff*d) * kaiser(d/float64(r.half))
+		sum += out[j]
+	}
+	// unity gain at DC regardless of phase
+	for j := range out {
+		out[j] /= sum
+	}
+}
+
+func (r *Resampler) Stream(samples [][2]float64) (n int, ok bool) {
+	for n < len(samples) {
+		if r.eof && r.i >= r.in {
+			break
+		}
+		if !r.fill(r.i + r.half) {
+			break
+		}
+		h := r.kernel
+		if r.filters != nil {
+			h = r.filters[r.p]
+		} else {
+			r.fillKernel(r.p, h)
+		}
+		start := r.i - r.half + 1 - r.base
+		var l, rr float64
+		for j, c := range h {
+			x := r.buf[start+j]
+			l += c * x[0]
+			rr += c * x[1]
+		}
+		samples[n] = [2]float64{l, rr}
+		n++
+
+		r.p += r.down
+		r.i += r.p / r.up
+		r.p %= r.up
+	}
+	return n, n > 0
+}
+
+// fill makes sure buf holds input samples up to (and including) index last,
+// reading from the source as needed. Past the end of the source the buffer is
+// padded with silence so the filter tail can drain.
+func (r *Resampler) fill(last int) bool {
+	r.compact()
+	for last-r.base >= len(r.buf) {
+		if r.eof {
+			r.buf = append(r.buf, [2]float64{})
+			continue
+		}
+		n, ok := r.s.Stream(r.chunk)
+		r.buf = append(r.buf, r.chunk[:n]...)
+		r.in += n
+		if !ok {
+			r.eof = true
+			r.err = r.s.Err()
+		}
+	}
+	return r.err == nil
+}
+
+// compact drops input samples which are no longer reachable by the filter.
+func (r *Resampler) compact() {
+	drop := r.i - r.half + 1 - r.base
+	if drop < cap(r.buf)/2 {
+		return
+	}
+	r.buf = append(r.buf[:0], r.buf[drop:]...)
+	r.base += drop
+}
+
+func (r *Resampler) Err() error {
+	return r.err
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// kaiser window evaluated at x in [-1, 1]
+func kaiser(x float64) float64 {
+	if x < -1 || x > 1 {
+		return 0
+	}
+	return bessel0(kaiserBeta*math.Sqrt(1-x*x)) / bessel0(kaiserBeta)
+}
+
+// bessel0 is the zeroth order modified Bessel function of the first kind.
+func bessel0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	for k := 1; k < 50; k++ {
+		term *= (x / (2 * float64(k))) * (x / (2 * float64(k)))
+		sum += term
+		if term < sum*1e-12 {
+			break
+		}
+	}
+	return sum
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
@@ -0,0 +1,108 @@
+package resample
+
+import (
+	"math"
+	"testing"
+
+	"github.com/faiface/beep"
+	"github.com/stretchr/testify/assert"
+)
+
+// sine generates `length` samples of a sin wave of peak amplitude 1
+func sine(freq float64, sampleRate, length int) beep.Streamer {
+	i := 0
+	return beep.StreamerFunc(func(samples [][2]float64) (n int, ok bool) {
+		for n = range samples {
+			if i >= length {
+				return n, n > 0
+			}
+			v := math.Sin(2 * math.Pi * freq * float64(i) / float64(sampleRate))
+			samples[n] = [2]float64{v, v}
+			i++
+		}
+		return len(samples), true
+	})
+}
+
+func readAll(s beep.Streamer) []float64 {
+	var out []float64
+	chunk := make([][2]float64, 300)
+	for {
+		n, ok := s.Stream(chunk)
+		for _, v := range chunk[:n] {
+			out = append(out, v[0])
+		}
+		if !ok {
+			return out
+		}
+	}
+}
+
+func rms(data []float64) float64 {
+	var sum float64
+	for _, d := range data {
+		sum += d * d
+	}
+	return math.Sqrt(sum / float64(len(data)))
+}
+
+// zeroCrossings counts rising zero crossings, which gives the frequency of a
+// pure tone without needing an FFT
+func zeroCrossings(data []float64) int {
+	c := 0
+	for i := 1; i < len(data); i++ {
+		if data[i-1] < 0 && data[i] >= 0 {
+			c++
+		}
+	}
+	return c
+}
+
+func TestUnityRatio(t *testing.T) {
+	out := readAll(New(DefaultQuality, 48_000, 48_000, sine(440, 48_000, 4800)))
+	in := readAll(sine(440, 48_000, 4800))
+
+	assert.Equal(t, len(in), len(out))
+	assert.InDeltaSlice(t, in, out, 1e-9)
+}
+
+func TestUpsample(t *testing.T) {
+	r := New(DefaultQuality, 44_100, 48_000, sine(440, 44_100, 44_100))
+	assert.Equal(t, 48_000, r.SampleRate())
+
+	out := readAll(r)
+	assert.Equal(t, 48_000, len(out))
+
+	// ignore the edges where the filter sees the implicit silence
+	steady := out[1000 : len(out)-1000]
+	assert.InDelta(t, 0.707, rms(steady), 0.005)
+	assert.InDelta(t, 440*float64(len(steady))/48_000, zeroCrossings(steady), 1)
+}
+
+func TestDownsample(t *testing.T) {
+	out := readAll(New(DefaultQuality, 192_000, 48_000, sine(1000, 192_000, 192_000)))
+	assert.Equal(t, 48_000, len(out))
+
+	steady := out[1000 : len(out)-1000]
+	assert.InDelta(t, 0.707, rms(steady), 0.005)
+	assert.InDelta(t, 1000*float64(len(steady))/48_000, zeroCrossings(steady), 1)
+}
+
+func TestDownsampleRejectsAliases(t *testing.T) {
+	// 30kHz is above the 24kHz Nyquist of the output and must not fold down
+	// to 18kHz
+	out := readAll(New(DefaultQuality, 192_000, 48_000, sine(30_000, 192_000, 192_000)))
+
+	steady := out[1000 : len(out)-1000]
+	assert.Less(t, rms(steady), 0.001)
+}
+
+func TestUnreducibleRatio(t *testing.T) {
+	// too many phases to precompute
+	r := New(8, 44_100, 47_999, sine(440, 44_100, 4410))
+	assert.Nil(t, r.filters)
+
+	out := readAll(r)
+	assert.InDelta(t, 4800, len(out), 1)
+	assert.InDelta(t, 0.707, rms(out[200:len(out)-200]), 0.01)
+}
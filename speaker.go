@@ -0,0 +1,25 @@
+//go:build !headless
+
+package main
+
+import (
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/speaker"
+)
+
+const audioOutputName = "default"
+
+// playSpeaker plays s through the default audio output, blocking until it
+// is drained, then returns any error s stopped with. bufferSize is the
+// speaker buffer length in samples.
+func playSpeaker(s beep.Streamer, sampleRate, bufferSize int) error {
+	if err := speaker.Init(beep.SampleRate(sampleRate), bufferSize); err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	speaker.Play(beep.Seq(s, beep.Callback(func() {
+		close(done)
+	})))
+	<-done
+	return s.Err()
+}
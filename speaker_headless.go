@@ -0,0 +1,17 @@
+//go:build headless
+
+package main
+
+import (
+	"errors"
+
+	"github.com/faiface/beep"
+)
+
+const audioOutputName = "unavailable (built headless)"
+
+// Built with the headless tag, which drops the dependency on the system
+// audio libraries entirely. Use a [Pacing] mode instead.
+func playSpeaker(s beep.Streamer, sampleRate, bufferSize int) error {
+	return errors.New("built without audio output support (headless)")
+}
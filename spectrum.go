@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rabidaudio/led-eq/eq"
+	"github.com/rabidaudio/led-eq/led"
+)
+
+// the range shown on each kind of axis; louder values are clipped
+const (
+	dbMin, dbMax         = -60.0, 0.0
+	linearMin, linearMax = 0.0, 1.0
+)
+
+const (
+	// how long a peak marker stays before falling
+	peakHold = time.Second
+	// how fast it then falls, in fractions of the axis a second
+	peakFall = 0.5
+	// columns for the axis labels
+	axisWidth = 5
+)
+
+// eighths of a cell, for bars which rise smoothly
+var blocks = []rune(" ▁▂▃▄▅▆▇█")
+
+// spectrum draws frames as vertical bars, one for each band, over a dB or
+// linear axis with the frequency of each band below. It fits itself to
+// width and height, in cells.
+type spectrum struct {
+	bins   eq.Bins
+	db     bool
+	colors led.Gradient // from the bottom of the axis to the top
+
+	width, height int
+
+	values []float64
+	peaks  []peak
+	last   time.Time
+}
+
+type peak struct {
+	value float64
+	until time.Time // when it starts falling
+}
+
+func newSpectrum(bins eq.Bins, db bool) *spectrum {
+	return &spectrum{bins: bins, db: db, colors: led.VU, width: 80, height: 24}
+}
+
+// setScale changes the bins and axis, clearing the peaks.
+func (s *spectrum) setScale(bins eq.Bins, db bool) {
+	s.bins, s.db = bins, db
+	s.values, s.peaks = nil, nil
+}
+
+func (s *spectrum) axis() (lo, hi float64) {
+	if s.db {
+		return dbMin, dbMax
+	}
+	return linearMin, linearMax
+}
+
+// push shows the frame, received at now, updating the peaks. Frames which
+// don't match the bins are ignored, as they are from before a change.
+func (s *spectrum) push(values []float64, now time.Time) {
+	if len(values) != s.bins.Len() {
+		return
+	}
+	s.values = append(s.values[:0], values...)
+	if len(s.peaks) != len(values) {
+		s.peaks = make([]peak, len(values))
+		for i := range s.peaks {
+			s.peaks[i].value = math.Inf(-1)
+		}
+	}
+	lo, hi := s.axis()
+	fall := 0.0
+	if !s.last.IsZero() {
+		fall = peakFall * (hi - lo) * now.Sub(s.last).Seconds()
+	}
+	s.last = now
+	for i, v := range values {
+		p := &s.peaks[i]
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			// never a peak, or it would stick; a held peak still falls
+			v = math.Inf(-1)
+		}
+		if v >= p.value {
+			*p = peak{value: v, until: now.Add(peakHold)}
+		} else if now.After(p.until) {
+			p.value = max(p.value-fall, v)
+		}
+	}
+}
+
+// level is how high v reaches on an axis rows tall, in eighths of a row.
+func (s *spectrum) level(v float64, rows int) int {
+	lo, hi := s.axis()
+	if math.IsNaN(v) || v <= lo {
+		return 0
+	}
+	return int(math.Round(min((v-lo)/(hi-lo), 1) * float64(rows*8)))
+}
+
+// View draws the chart: the bars with the axis on the left, a baseline,
+// then the frequency labels.
+func (s *spectrum) View() string {
+	rows := max(s.height-2, 2)
+	bands := s.bins.Len()
+	if bands <= 0 {
+		return ""
+	}
+	avail := max(s.width-axisWidth-1, 1)
+	gap := 0
+	if avail >= 2*bands {
+		gap = 1
+	}
+	barWidth := max((avail-gap*bands)/bands, 1)
+	// bars which don't fit are left off
+	shown := min(bands, avail/(barWidth+gap))
+
+	ticks := s.ticks(rows)
+	var b strings.Builder
+	for row := rows - 1; row >= 0; row-- {
+		if label, ok := ticks[row]; ok {
+			fmt.Fprintf(&b, "%*s┤", axisWidth, label)
+		} else {
+			fmt.Fprintf(&b, "%*s│", axisWidth, "")
+		}
+		c := s.colors.At((float64(row) + 0.5) / float64(rows))
+		color := fmt.Sprintf("\x1b[38;2;%d;%d;%dm", c.R, c.G, c.B)
+		for i := range shown {
+			cell, marker := ' ', false
+			if i < len(s.values) {
+				fill := s.level(s.values[i], rows) - row*8
+				cell = blocks[min(max(fill, 0), 8)]
+				p := s.level(s.peaks[i].value, rows)
+				// the marker sits in the row the peak reaches, if that is
+				// above the bar
+				marker = p > 0 && fill <= 0 && (p-1)/8 == row
+			}
+			switch {
+			case marker:
+				b.WriteString("\x1b[97m" + strings.Repeat("▔", barWidth))
+			case cell == ' ':
+				b.WriteString("\x1b[0m" + strings.Repeat(" ", barWidth))
+			default:
+				b.WriteString(color + strings.Repeat(string(cell), barWidth))
+			}
+			b.WriteString(strings.Repeat(" ", gap))
+		}
+		b.WriteString("\x1b[0m\n")
+	}
+	fmt.Fprintf(&b, "%*s└%s\n", axisWidth, "", strings.Repeat("─", shown*(barWidth+gap)))
+	b.WriteString(strings.Repeat(" ", axisWidth+1))
+	b.WriteString(s.labels(shown, barWidth+gap, avail))
+	return b.String()
+}
+
+// ticks labels the axis on rows tall, keyed by row from the bottom, at a
+// step which leaves a couple of rows between labels.
+func (s *spectrum) ticks(rows int) map[int]string {
+	lo, hi := s.axis()
+	steps := []float64{0.1, 0.25, 0.5, 1}
+	unit := ""
+	if s.db {
+		steps = []float64{6, 10, 20, 30, 60}
+		unit = "dB"
+	}
+	step := steps[len(steps)-1]
+	for _, st := range steps {
+		if (hi-lo)/st*3 <= float64(rows) {
+			step = st
+			break
+		}
+	}
+	ticks := make(map[int]string)
+	for k := 0; hi-float64(k)*step >= lo-step/2; k++ {
+		v := hi - float64(k)*step
+		row := int(math.Ceil((v-lo)/(hi-lo)*float64(rows))) - 1
+		row = min(max(row, 0), rows-1)
+		if _, ok := ticks[row]; !ok {
+			ticks[row] = strconv.FormatFloat(v, 'g', 3, 64) + unit
+		}
+	}
+	return ticks
+}
+
+// labels places the center frequency under each bar, slot cells apart,
+// skipping any which would run into the one before or past width.
+func (s *spectrum) labels(bars, slot, width int) string {
+	var b strings.Builder
+	for i := range bars {
+		col := i * slot
+		label := formatHz(center(s.bins.Bounds(i)))
+		if b.Len() > 0 && col <= b.Len() || col+len(label) > width {
+			continue
+		}
+		b.WriteString(strings.Repeat(" ", col-b.Len()))
+		b.WriteString(label)
+	}
+	return b.String()
+}
+
+// center is the middle of a band as heard, which is the geometric mean
+// for bands above 0 Hz.
+func center(lo, hi float64) float64 {
+	if lo <= 0 {
+		return (lo + hi) / 2
+	}
+	return math.Sqrt(lo * hi)
+}
+
+// formatHz writes a frequency compactly, as 63 or 1.2k.
+func formatHz(f float64) string {
+	if f < 1000 {
+		return strconv.FormatFloat(math.Round(f), 'f', -1, 64)
+	}
+	k := strconv.FormatFloat(f/1000, 'f', 1, 64)
+	return strings.TrimSuffix(k, ".0") + "k"
+}
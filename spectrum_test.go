@@ -0,0 +1,87 @@
+package main
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rabidaudio/led-eq/eq"
+	"github.com/stretchr/testify/assert"
+)
+
+var ansi = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+func TestFormatHz(t *testing.T) {
+	assert.Equal(t, "63", formatHz(62.9))
+	assert.Equal(t, "1k", formatHz(1000))
+	assert.Equal(t, "1.5k", formatHz(1500))
+	assert.Equal(t, "16k", formatHz(16_000))
+}
+
+func TestSpectrumFitsTerminal(t *testing.T) {
+	for _, size := range [][2]int{{80, 24}, {40, 10}, {200, 60}, {10, 4}} {
+		s := newSpectrum(eq.ExponentialBins(20, 20_000, 16), false)
+		s.width, s.height = size[0], size[1]
+		s.push(make([]float64, 16), time.Now())
+		lines := strings.Split(s.View(), "\n")
+		assert.Len(t, lines, max(size[1], 4), "%v", size)
+		for _, l := range lines {
+			assert.LessOrEqual(t, utf8.RuneCountInString(ansi.ReplaceAllString(l, "")), max(size[0], 8), "%v %q", size, l)
+		}
+	}
+}
+
+func TestSpectrumBars(t *testing.T) {
+	s := newSpectrum(eq.LinearBins(0, 4000, 2), false)
+	s.width, s.height = 13, 4 // 2 rows of bars 2 wide
+	s.push([]float64{1, 0.25}, time.Now())
+	s.push([]float64{0, 0.25}, time.Now())
+	assert.Equal(t,
+		"    1┤▔▔    \n"+
+			"    0┤   ▄▄ \n"+
+			"     └──────\n"+
+			"      1k 2.8k",
+		ansi.ReplaceAllString(s.View(), ""))
+}
+
+func TestSpectrumTicks(t *testing.T) {
+	s := newSpectrum(eq.LinearBins(0, 1000, 1), true)
+	ticks := s.ticks(18)
+	assert.Equal(t, "0dB", ticks[17])
+	assert.Equal(t, "-60dB", ticks[0])
+	assert.Equal(t, "-30dB", ticks[8])
+}
+
+func TestSpectrumLabelsSkipOverlaps(t *testing.T) {
+	s := newSpectrum(eq.ArbitraryBins(1000, 2000, 3000, 4000), false)
+	assert.Equal(t, "1.4k  3.5k", s.labels(3, 3, 10))
+	assert.Equal(t, "1.4k", s.labels(3, 3, 9))
+}
+
+func TestPeakHold(t *testing.T) {
+	s := newSpectrum(eq.LinearBins(0, 1000, 1), false)
+	start := time.Now()
+	s.push([]float64{0.8}, start)
+	s.push([]float64{0.2}, start.Add(peakHold/2))
+	assert.Equal(t, 0.8, s.peaks[0].value, "held")
+	s.push([]float64{0.2}, start.Add(peakHold+100*time.Millisecond))
+	assert.InDelta(t, 0.8-peakFall*0.6, s.peaks[0].value, 1e-9, "falling")
+	s.push([]float64{0.2}, start.Add(10*time.Second))
+	assert.Equal(t, 0.2, s.peaks[0].value, "not below the bar")
+
+	for _, v := range []float64{math.NaN(), math.Inf(1)} {
+		s.push([]float64{v}, start.Add(10*time.Second))
+		assert.Equal(t, 0.2, s.peaks[0].value, "%v is skipped", v)
+	}
+	s.push([]float64{0.1}, start.Add(30*time.Second))
+	assert.Equal(t, 0.1, s.peaks[0].value, "falls again")
+
+	// the dB axis starts at -60 dB, below which bars are empty
+	s.setScale(eq.LinearBins(0, 1000, 1), true)
+	s.push([]float64{math.Inf(-1)}, start)
+	assert.Equal(t, 0, s.level(s.peaks[0].value, 10))
+	assert.Equal(t, 40, s.level(-30, 10))
+}
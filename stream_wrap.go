@@ -1,7 +1,11 @@
 package main
 
 import (
+	"fmt"
 	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/faiface/beep"
 	"github.com/rabidaudio/led-eq/eq"
@@ -10,28 +14,88 @@ import (
 
 type EQStreamWrapper struct {
 	beep.Streamer
-	eq *eq.EQ
-	d  Display
+	eq   *eq.EQ
+	post eq.Chain
+	d    Display
 
 	buf  []float64
 	bufi int
 	res  []float64
+	pos  int64 // sample position of buf[0]
+
+	// now is the clock used to timestamp frames, time.Now if nil
+	now func() time.Time
+	// metrics, if set, measures decoding, computing and underruns
+	metrics *pipelineMetrics
+
+	pending atomic.Pointer[reconfig]
+	mu      sync.Mutex // guards eq against Reconfigure
 
 	err error
 }
 
+type reconfig struct {
+	eq   eq.EQ
+	post eq.Chain
+	d    Display
+}
+
 var _ beep.Streamer = (*EQStreamWrapper)(nil)
 
+// Reconfigure replaces the analysis settings, post-processing and display.
+// It is safe to call from any goroutine while streaming: the change is
+// picked up between frames, so every frame is computed and rendered with a
+// single configuration. Samples already buffered are kept, so changing N
+// doesn't drop audio.
+func (sw *EQStreamWrapper) Reconfigure(e eq.EQ, post eq.Chain, d Display) error {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	if e.SampleRate != sw.eq.SampleRate {
+		return fmt.Errorf("can't change sample rate from %v to %v while streaming", sw.eq.SampleRate, e.SampleRate)
+	}
+	sw.pending.Store(&reconfig{eq: e, post: post, d: d})
+	return nil
+}
+
+func (sw *EQStreamWrapper) applyPending() {
+	p := sw.pending.Swap(nil)
+	if p == nil {
+		return
+	}
+	sw.mu.Lock()
+	sw.eq = &p.eq
+	sw.mu.Unlock()
+	sw.post = p.post
+	sw.d = p.d
+	if len(sw.res) != sw.eq.OutBins.Len() {
+		sw.res = nil
+	}
+}
+
+// Stream reads from the wrapped streamer and renders a frame for each full
+// window. Frames are timestamped assuming the samples are handed to the
+// output as soon as Stream returns, as a speaker does.
 func (sw *EQStreamWrapper) Stream(samples [][2]float64) (n int, ok bool) {
+	sw.applyPending()
 	if sw.buf == nil {
 		sw.buf = make([]float64, 0, sw.eq.N)
 		sw.bufi = 0
 	}
+	now := time.Now
+	if sw.now != nil {
+		now = sw.now
+	}
+	streamed := now()
+	chunkStart := sw.pos + int64(sw.bufi)
 
 	n, ok = sw.Streamer.Stream(samples)
 	if !ok {
 		return n, ok
 	}
+	sw.metrics.decoded(now().Sub(streamed))
+	defer func() {
+		sw.metrics.streamed(now().Sub(streamed), samplesToDuration(int64(n), sw.eq.SampleRate))
+	}()
 	// make sure there's enough room in the buffer
 	rem := len(sw.buf) - sw.bufi
 	if rem < n {
@@ -41,8 +105,8 @@ func (sw *EQStreamWrapper) Stream(samples [][2]float64) (n int, ok bool) {
 	wav.ToMono(samples[:n], sw.buf[sw.bufi:(sw.bufi+n)])
 	sw.bufi += n
 
-	// if a full N is available
-	if sw.bufi >= sw.eq.N {
+	// for each full N available
+	for sw.bufi >= sw.eq.N {
 		if sw.res == nil {
 			sw.res = make([]float64, sw.eq.OutBins.Len())
 		}
@@ -50,9 +114,19 @@ func (sw *EQStreamWrapper) Stream(samples [][2]float64) (n int, ok bool) {
 			sw.res[i] = 0
 		}
 		// compute and render
+		start := now()
 		sw.eq.Compute(sw.buf[:sw.eq.N], sw.res)
+		sw.post.Process(sw.res)
+		sw.metrics.computed(now().Sub(start))
 		if sw.d != nil && !reflect.ValueOf(sw.d).IsNil() {
-			err := sw.d.Render(sw.res)
+			var err error
+			if td, ok := sw.d.(TimedDisplay); ok {
+				center := sw.pos + int64(sw.eq.N/2)
+				at := streamed.Add(samplesToDuration(center-chunkStart, sw.eq.SampleRate))
+				err = td.RenderAt(FrameTime{Sample: sw.pos, At: at}, sw.res)
+			} else {
+				err = sw.d.Render(sw.res)
+			}
 			if err != nil {
 				sw.err = err
 				return n, false
@@ -60,10 +134,18 @@ func (sw *EQStreamWrapper) Stream(samples [][2]float64) (n int, ok bool) {
 		}
 		sw.buf = sw.buf[sw.eq.N:] // advance buffer
 		sw.bufi -= sw.eq.N
+		sw.pos += int64(sw.eq.N)
 	}
 	return n, ok
 }
 
+// Gain is the normalization gain currently applied.
+func (sw *EQStreamWrapper) Gain() float64 {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.eq.Normalize
+}
+
 func (sw *EQStreamWrapper) Err() error {
 	if sw.err != nil {
 		return sw.err
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/rabidaudio/led-eq/eq"
+	"github.com/stretchr/testify/assert"
+)
+
+// notifyingRecorder is a recordingDisplay which hands each frame count to
+// another goroutine
+type notifyingRecorder struct {
+	recordingDisplay
+	notify chan int
+}
+
+func (d *notifyingRecorder) Render(values []float64) error {
+	d.recordingDisplay.Render(values)
+	d.notify <- len(d.frames)
+	return nil
+}
+
+func TestReconfigureMidStream(t *testing.T) {
+	const length = 4 * 48_000
+	a := eq.EQ{SampleRate: 48_000, N: 1024, OutBins: eq.ExponentialBins(20, 20_000, 8), Normalize: 1}
+	b := eq.EQ{SampleRate: 48_000, N: 2048, OutBins: eq.LinearBins(0, 2000, 4), Normalize: 3}
+	configs := map[int]eq.EQ{a.OutBins.Len(): a, b.OutBins.Len(): b}
+
+	d := &notifyingRecorder{notify: make(chan int)}
+	e := a
+	wrap := EQStreamWrapper{Streamer: sine(440, 48_000, length), eq: &e, d: d}
+
+	// flip between configs from another goroutine while streaming
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for n := range d.notify {
+			if n%5 != 0 {
+				continue
+			}
+			next := a
+			if n%10 == 0 {
+				next = b
+			}
+			assert.NoError(t, wrap.Reconfigure(next, nil, d))
+		}
+	}()
+	err := Pace(&wrap, 48_000, 512, AsFastAsPossible)
+	close(d.notify)
+	wg.Wait()
+	assert.NoError(t, err)
+
+	// every frame must be exactly the analysis of the next contiguous window
+	// using one of the configs, identified by its bin count
+	signal := make([]float64, length)
+	for i := range signal {
+		signal[i] = math.Sin(2 * math.Pi * 440 * float64(i) / 48_000)
+	}
+	pos, switches := 0, 0
+	for i, f := range d.frames {
+		c, ok := configs[len(f)]
+		if !assert.True(t, ok, "frame %d has %d bins", i, len(f)) {
+			return
+		}
+		if i > 0 && len(f) != len(d.frames[i-1]) {
+			switches++
+		}
+		expected := make([]float64, c.OutBins.Len())
+		c.Compute(signal[pos:pos+c.N], expected)
+		assert.InDeltaSlice(t, expected, f, 1e-9, "frame %d", i)
+		pos += c.N
+	}
+	assert.Greater(t, switches, 1)
+	assert.Less(t, length-pos, 2048, "no samples skipped")
+}
+
+func TestReconfigureSampleRate(t *testing.T) {
+	e := eq.New(48_000, 1024, 8)
+	wrap := EQStreamWrapper{Streamer: sine(440, 48_000, 48_000), eq: &e}
+	assert.Error(t, wrap.Reconfigure(eq.New(44_100, 1024, 8), nil, nil))
+}
+
+func TestReconfigureShrinkN(t *testing.T) {
+	e := eq.New(48_000, 4096, 8)
+	d := &recordingDisplay{}
+	wrap := EQStreamWrapper{Streamer: sine(440, 48_000, 48_000), eq: &e, d: d}
+
+	buf := make([][2]float64, 1000)
+	wrap.Stream(buf)
+	assert.NoError(t, wrap.Reconfigure(eq.New(48_000, 256, 8), nil, d))
+	wrap.Stream(buf)
+
+	// both chunks were buffered, so 2000 samples make 7 frames at once
+	assert.Len(t, d.frames, 7)
+}
+
+func TestGainWhileStreaming(t *testing.T) {
+	// an unset gain counts as 1 without the EQ being written, so reading
+	// it while computing isn't a race
+	e := eq.EQ{SampleRate: 48_000, N: 1024, OutBins: eq.ExponentialBins(20, 20_000, 8)}
+	wrap := EQStreamWrapper{Streamer: sine(440, 48_000, 48_000), eq: &e, d: &recordingDisplay{}}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 100 {
+			assert.Equal(t, 0.0, wrap.Gain())
+		}
+	}()
+	assert.NoError(t, Pace(&wrap, 48_000, 1024, AsFastAsPossible))
+	<-done
+	assert.Equal(t, 1.0, eq.New(48_000, 1024, 8).Normalize)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/rabidaudio/led-eq/config"
+	"github.com/rabidaudio/led-eq/eq"
+)
+
+// Tuner changes the EQ settings of the running pipeline.
+type Tuner interface {
+	// Tune applies change to a copy of the settings and switches to them,
+	// returning an error if they are invalid
+	Tune(change func(c *config.EQ)) error
+}
+
+// Transport controls the playback of a recording, when replaying one.
+// [frames.Player] is one.
+type Transport interface {
+	Pause()
+	Resume()
+	Paused() bool
+	Seek(t time.Duration)
+	Position() time.Duration
+	Duration() time.Duration
+}
+
+// how much each press of the gain keys changes the gain
+const gainStep = 1.25
+
+// the lowest edge used when switching to bins which can't start at 0
+const defaultMinHz = 20
+
+// helpText lists the keys of the terminal display.
+var helpText = []string{
+	" keys                         ",
+	"  + / -   raise / lower gain  ",
+	"  d       toggle dB           ",
+	"  b       next bin preset     ",
+	"  ] / [   more / fewer bars   ",
+	"  space   freeze              ",
+	"  ?       show / hide help    ",
+	"  q       quit                ",
+}
+
+// replayHelp lists the keys when replaying, where the settings are fixed.
+var replayHelp = []string{
+	" keys                         ",
+	"  space   pause / play        ",
+	"  ← / →   back / forward 5s   ",
+	"  f       freeze              ",
+	"  ?       show / hide help    ",
+	"  q       quit                ",
+}
+
+// how far each press of the seek keys moves
+const seekStep = 5 * time.Second
+
+// formatPosition writes a position in a recording as m:ss.
+func formatPosition(d time.Duration) string {
+	s := int(d.Round(time.Second) / time.Second)
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+func scaleGain(f float64) func(c *config.EQ) {
+	return func(c *config.EQ) {
+		c.Normalize *= f
+	}
+}
+
+func toggleDB(c *config.EQ) {
+	c.OutputDB = !c.OutputDB
+}
+
+// nextPreset switches to the next of [config.Presets] over the same range,
+// keeping about as many bars. Explicit edges go to the first preset.
+func nextPreset(c *config.EQ) {
+	bands := bandCount(c.Bins)
+	next := config.Presets[(slices.Index(config.Presets, c.Bins.Preset)+1)%len(config.Presets)]
+	b := asPreset(c.Bins)
+	b.Preset = next
+	b.Count = bands
+	if b.Preset != "linear" && b.Min <= 0 {
+		b.Min = defaultMinHz
+	}
+	if b.Preset == "octave" {
+		b.Count = max(int(math.Round(float64(bands)/math.Log2(b.Max/b.Min))), 1)
+	}
+	c.Bins = b
+}
+
+// addBars changes the number of bars by n, or the bars per octave for
+// octave bins.
+func addBars(n int) func(c *config.EQ) {
+	return func(c *config.EQ) {
+		c.Bins = asPreset(c.Bins)
+		c.Bins.Count = max(c.Bins.Count+n, 1)
+	}
+}
+
+// asPreset turns explicit edges into the preset spanning them: exponential,
+// or linear if they start at 0.
+func asPreset(b config.Bins) config.Bins {
+	if b.Preset != "" {
+		return b
+	}
+	p := config.Bins{Preset: "exponential", Count: len(b.Edges) - 1, Min: b.Edges[0], Max: b.Edges[len(b.Edges)-1]}
+	if p.Min <= 0 {
+		p.Preset = "linear"
+	}
+	return p
+}
+
+// bandCount is how many bands the bins make.
+func bandCount(b config.Bins) int {
+	switch b.Preset {
+	case "":
+		return len(b.Edges) - 1
+	case "octave":
+		return eq.OctaveBins(b.Min, b.Max, b.Count).Len()
+	default:
+		return b.Count
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/rabidaudio/led-eq/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextPreset(t *testing.T) {
+	c := config.EQ{Bins: config.Bins{Preset: "linear", Count: 8, Min: 0, Max: 4000}}
+	nextPreset(&c)
+	assert.Equal(t, config.Bins{Preset: "exponential", Count: 8, Min: defaultMinHz, Max: 4000}, c.Bins)
+	nextPreset(&c)
+	assert.Equal(t, config.Bins{Preset: "octave", Count: 1, Min: defaultMinHz, Max: 4000}, c.Bins)
+	nextPreset(&c)
+	assert.Equal(t, "linear", c.Bins.Preset)
+	assert.Equal(t, bandCount(config.Bins{Preset: "octave", Count: 1, Min: defaultMinHz, Max: 4000}), c.Bins.Count)
+}
+
+func TestNextPresetFromEdges(t *testing.T) {
+	c := config.EQ{Bins: config.Bins{Edges: []float64{100, 200, 400, 800}}}
+	nextPreset(&c)
+	assert.Equal(t, config.Bins{Preset: "linear", Count: 3, Min: 100, Max: 800}, c.Bins)
+}
+
+func TestAddBarsToEdges(t *testing.T) {
+	c := config.EQ{Bins: config.Bins{Edges: []float64{100, 200, 400, 800}}}
+	addBars(1)(&c)
+	assert.Equal(t, config.Bins{Preset: "exponential", Count: 4, Min: 100, Max: 800}, c.Bins)
+
+	c.Bins = config.Bins{Edges: []float64{0, 100}}
+	addBars(-1)(&c)
+	assert.Equal(t, config.Bins{Preset: "linear", Count: 1, Min: 0, Max: 100}, c.Bins)
+}
@@ -4,26 +4,45 @@ import (
 	"fmt"
 	"os"
 	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/NimbleMarkets/ntcharts/sparkline"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/rabidaudio/led-eq/config"
 	"github.com/rabidaudio/led-eq/eq"
 )
 
-// arbitrary, just make the scale bigger cheaply
-const scaleFactor = 10
-
+// TerminalDisplay draws the bands as a spectrum which fills the terminal,
+// with keys to tune the EQ while it runs. It keeps running across reloads,
+// being told of changes to the EQ with [TerminalDisplay.SetEQ].
 type TerminalDisplay struct {
-	eq  *eq.EQ
-	msg chan tea.Msg
-	sl  sparkline.Model
-	max float64
+	settings  atomic.Pointer[terminalSettings]
+	tuner     Tuner
+	transport Transport   // set when replaying
+	frames    *frameQueue // latest frame only
+	done      chan struct{}
+	once      sync.Once
+	view      *spectrum
+
+	frozen bool
+	help   bool
+	err    error // from the last change
+}
+
+// terminalSettings are what the EQ is running with.
+type terminalSettings struct {
+	cfg config.EQ
+	eq  eq.EQ
 }
 
 type render struct{ data []float64 }
 type done struct{}
 
+// tuned is sent once a change from a key has been applied.
+type tuned struct{ err error }
+
 var _ tea.Model = done{}
 
 func (done) Init() tea.Cmd {
@@ -40,7 +59,12 @@ func (done) View() string {
 
 func (td *TerminalDisplay) awaitNext() tea.Cmd {
 	return tea.Every(1*time.Second/60.0, func(t time.Time) tea.Msg {
-		return <-td.msg
+		select {
+		case data := <-td.frames.C():
+			return render{data: data}
+		case <-td.done:
+			return done{}
+		}
 	})
 }
 
@@ -54,47 +78,168 @@ func (td *TerminalDisplay) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		switch msg.String() {
 		case "q", "ctrl+c":
 			return done{}, tea.Quit
+		case "+", "=":
+			return td, td.tune(scaleGain(gainStep))
+		case "-", "_":
+			return td, td.tune(scaleGain(1 / gainStep))
+		case "d":
+			return td, td.tune(toggleDB)
+		case "b":
+			return td, td.tune(nextPreset)
+		case "]":
+			return td, td.tune(addBars(1))
+		case "[":
+			return td, td.tune(addBars(-1))
+		case " ":
+			if td.transport != nil {
+				td.togglePause()
+			} else {
+				td.frozen = !td.frozen
+			}
+		case "f":
+			td.frozen = !td.frozen
+		case "left":
+			td.seek(-seekStep)
+		case "right":
+			td.seek(seekStep)
+		case "?", "h":
+			td.help = !td.help
+		case "esc":
+			td.help = false
 		}
+	case tuned:
+		td.err = msg.err
+	case tea.WindowSizeMsg:
+		td.view.width = msg.Width
+		td.view.height = msg.Height - 1 // for the status line
 	case done:
 		return done{}, tea.Quit
 	case render:
-		td.max = slices.Max(msg.data)
-		td.sl.PushAll(msg.data)
-		td.sl.Draw()
+		if td.frozen {
+			return td, td.awaitNext()
+		}
+		if e := td.settings.Load().eq; !slices.Equal(e.OutBins, td.view.bins) || e.OutputDB != td.view.db {
+			td.view.setScale(e.OutBins, e.OutputDB)
+		}
+		td.view.push(msg.data, time.Now())
 		return td, td.awaitNext()
 	}
 	return td, nil
 }
 
+// tune applies a change in the background, as rebuilding the displays can
+// take a while.
+func (td *TerminalDisplay) tune(change func(c *config.EQ)) tea.Cmd {
+	if td.tuner == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		return tuned{td.tuner.Tune(change)}
+	}
+}
+
+func (td *TerminalDisplay) togglePause() {
+	if td.transport.Paused() {
+		td.transport.Resume()
+	} else {
+		td.transport.Pause()
+	}
+}
+
+func (td *TerminalDisplay) seek(by time.Duration) {
+	if td.transport != nil {
+		td.transport.Seek(td.transport.Position() + by)
+	}
+}
+
 func (td *TerminalDisplay) View() string {
-	return fmt.Sprintf("max: %f\n", td.max) + td.sl.View()
+	chart := td.view.View()
+	if td.help {
+		keys := helpText
+		if td.transport != nil {
+			keys = replayHelp
+		}
+		// over the top left of the chart
+		lines := strings.Split(chart, "\n")
+		for i, l := range keys {
+			if i < len(lines) {
+				lines[i] = "\x1b[7m" + l + "\x1b[0m"
+			}
+		}
+		chart = strings.Join(lines, "\n")
+	}
+	return td.status() + "\n" + chart
+}
+
+// status shows the current settings, or what went wrong changing them.
+func (td *TerminalDisplay) status() string {
+	s := td.settings.Load()
+	bins := s.eq.OutBins
+	scale := "linear"
+	if s.eq.OutputDB {
+		scale = "dB"
+	}
+	preset := s.cfg.Bins.Preset
+	if preset == "" {
+		preset = "custom"
+	}
+	line := fmt.Sprintf("%s, %s %d bands from %s to %s Hz",
+		scale, preset, bins.Len(), formatHz(bins[0]), formatHz(bins[len(bins)-1]))
+	if td.tuner != nil {
+		line = fmt.Sprintf("gain %.3g, ", s.eq.Normalize) + line
+	}
+	if t := td.transport; t != nil {
+		line = formatPosition(t.Position()) + " / " + formatPosition(t.Duration()) + ", " + line
+		if t.Paused() {
+			line += ", paused"
+		}
+	}
+	if td.frozen {
+		line += ", frozen"
+	}
+	if td.err != nil {
+		return line + " (" + td.err.Error() + ")"
+	}
+	return line + " (? for help)"
 }
 
 var _ Display = (*TerminalDisplay)(nil)
 var _ tea.Model = (*TerminalDisplay)(nil)
 
-func NewTerminalDisplay(eq *eq.EQ) *TerminalDisplay {
-	sl := sparkline.New(eq.OutBins.Len(), scaleFactor)
-	td := TerminalDisplay{eq: eq, sl: sl}
-	td.msg = make(chan tea.Msg)
+// NewTerminalDisplay draws the output of e, built from c. Keys change the
+// settings with tuner, which may be nil to leave them fixed.
+func NewTerminalDisplay(c config.EQ, e *eq.EQ, tuner Tuner) *TerminalDisplay {
+	td := TerminalDisplay{view: newSpectrum(e.OutBins, e.OutputDB), tuner: tuner}
+	td.SetEQ(c, *e)
+	td.frames = newFrameQueue(1)
+	td.done = make(chan struct{})
 	return &td
 }
 
+// SetEQ updates the bands, axis and status for a new EQ, built from c.
+// Frames from the old one are ignored if their bands no longer match.
+func (td *TerminalDisplay) SetEQ(c config.EQ, e eq.EQ) {
+	td.settings.Store(&terminalSettings{cfg: c, eq: e})
+}
+
+// SetTransport lets keys pause and seek t. It must be called before
+// [TerminalDisplay.Run].
+func (td *TerminalDisplay) SetTransport(t Transport) {
+	td.transport = t
+}
+
 func (td *TerminalDisplay) Render(values []float64) error {
-	v := make([]float64, len(values))
-	for i := range values {
-		v[i] = values[i] * scaleFactor
-	}
-	td.msg <- render{data: v}
+	// never wait for the UI, it will pick up the latest frame when it's ready
+	td.frames.Push(slices.Clone(values))
 	return nil
 }
 
 func (td *TerminalDisplay) Done() {
-	td.msg <- done{}
+	td.once.Do(func() { close(td.done) })
 }
 
 func (td *TerminalDisplay) Run() {
-	if _, err := tea.NewProgram(td, tea.WithFPS(90)).Run(); err != nil {
+	if _, err := tea.NewProgram(td, tea.WithFPS(90), tea.WithAltScreen()).Run(); err != nil {
 		fmt.Println("Error running program:", err)
 		os.Exit(1)
 	}
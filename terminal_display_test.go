@@ -0,0 +1,132 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/rabidaudio/led-eq/config"
+	"github.com/rabidaudio/led-eq/eq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTerminalDisplayResize(t *testing.T) {
+	e := eq.New(48_000, 1024, 8)
+	td := NewTerminalDisplay(config.EQ{}, &e, nil)
+	td.Update(tea.WindowSizeMsg{Width: 60, Height: 15})
+	assert.NoError(t, td.Render(make([]float64, 8)))
+	td.Update(render{data: <-td.frames.C()})
+	lines := strings.Split(td.View(), "\n")
+	assert.Len(t, lines, 15)
+	assert.Equal(t, "linear, custom 8 bands from 20 to 20k Hz (? for help)", lines[0])
+}
+
+func TestTerminalDisplaySetEQ(t *testing.T) {
+	e := eq.New(48_000, 1024, 8)
+	td := NewTerminalDisplay(config.EQ{}, &e, nil)
+	e.OutBins = eq.LinearBins(0, 1000, 4)
+	e.OutputDB = true
+	td.SetEQ(config.EQ{}, e)
+	td.Update(render{data: make([]float64, 4)})
+	assert.Equal(t, e.OutBins, td.view.bins)
+	assert.True(t, td.view.db)
+	assert.Len(t, td.view.values, 4)
+}
+
+// fakeTuner applies changes to its settings and tells the display, as the
+// pipeline would.
+type fakeTuner struct {
+	cfg config.EQ
+	td  *TerminalDisplay
+}
+
+func (f *fakeTuner) Tune(change func(c *config.EQ)) error {
+	c := f.cfg
+	change(&c)
+	e, err := c.Build(48_000)
+	if err != nil {
+		return err
+	}
+	f.cfg = c
+	f.td.SetEQ(c, e)
+	return nil
+}
+
+// press sends a key to the display, running the command it returns.
+func press(td *TerminalDisplay, key string) {
+	msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+	if key == " " {
+		msg = tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(key)}
+	}
+	_, cmd := td.Update(msg)
+	if cmd != nil {
+		td.Update(cmd())
+	}
+}
+
+func TestTerminalDisplayKeys(t *testing.T) {
+	c := config.EQ{FPS: 60, Normalize: 2, Bins: config.Bins{Preset: "exponential", Count: 16, Min: 20, Max: 20_000}}
+	e, err := c.Build(48_000)
+	assert.NoError(t, err)
+	tuner := &fakeTuner{cfg: c}
+	td := NewTerminalDisplay(c, &e, tuner)
+	tuner.td = td
+	assert.Equal(t, "gain 2, linear, exponential 16 bands from 20 to 20k Hz (? for help)", td.status())
+
+	press(td, "+")
+	press(td, "d")
+	press(td, "]")
+	assert.Equal(t, "gain 2.5, dB, exponential 17 bands from 20 to 20k Hz (? for help)", td.status())
+	press(td, "-")
+	press(td, "b")
+	assert.Equal(t, "gain 2, dB, octave 20 bands from 20 to 20k Hz (? for help)", td.status())
+	assert.Equal(t, 2, tuner.cfg.Bins.Count, "bands per octave")
+
+	press(td, " ")
+	assert.Contains(t, td.status(), ", frozen")
+	td.Update(render{data: make([]float64, 20)})
+	assert.Nil(t, td.view.values, "frozen")
+
+	press(td, "?")
+	assert.Contains(t, td.View(), helpText[1])
+	press(td, "?")
+	assert.NotContains(t, td.View(), helpText[1])
+}
+
+// fakeTransport is a recording which only moves when told to.
+type fakeTransport struct {
+	paused   bool
+	position time.Duration
+}
+
+func (f *fakeTransport) Pause()                  { f.paused = true }
+func (f *fakeTransport) Resume()                 { f.paused = false }
+func (f *fakeTransport) Paused() bool            { return f.paused }
+func (f *fakeTransport) Seek(t time.Duration)    { f.position = min(max(t, 0), f.Duration()) }
+func (f *fakeTransport) Position() time.Duration { return f.position }
+func (f *fakeTransport) Duration() time.Duration { return 90 * time.Second }
+
+func TestTerminalDisplayTransport(t *testing.T) {
+	e := eq.New(48_000, 1024, 8)
+	td := NewTerminalDisplay(config.EQ{}, &e, nil)
+	tr := &fakeTransport{}
+	td.SetTransport(tr)
+	assert.Equal(t, "0:00 / 1:30, linear, custom 8 bands from 20 to 20k Hz (? for help)", td.status())
+
+	press(td, " ")
+	assert.True(t, tr.paused)
+	assert.Contains(t, td.status(), ", paused")
+	td.Update(tea.KeyMsg{Type: tea.KeyRight})
+	td.Update(tea.KeyMsg{Type: tea.KeyRight})
+	assert.Equal(t, 10*time.Second, tr.position)
+	td.Update(tea.KeyMsg{Type: tea.KeyLeft})
+	assert.True(t, strings.HasPrefix(td.status(), "0:05 / 1:30"))
+	press(td, " ")
+	assert.False(t, tr.paused)
+
+	press(td, "f")
+	assert.Contains(t, td.status(), ", frozen")
+	press(td, "?")
+	assert.Contains(t, td.View(), replayHelp[1])
+}
@@ -0,0 +1,294 @@
+// Package web serves a browser visualizer of the EQ: a static page which
+// draws frames streamed over a WebSocket, and a REST API for the settings.
+package web
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+//go:embed static
+var static embed.FS
+
+// Settings are the EQ settings which can be read and changed at runtime.
+type Settings struct {
+	// Bins are the edges of the bands, in Hz
+	Bins []float64 `json:"bins"`
+	// Gain is applied to the band magnitudes
+	Gain float64 `json:"gain"`
+	// DB outputs bands in dB
+	DB bool `json:"db"`
+}
+
+// Update is a change to the settings; fields left out are unchanged.
+type Update struct {
+	Bins []float64 `json:"bins,omitempty"`
+	Gain *float64  `json:"gain,omitempty"`
+	DB   *bool     `json:"db,omitempty"`
+}
+
+// Apply returns s with the update applied.
+func (u Update) Apply(s Settings) Settings {
+	if u.Bins != nil {
+		s.Bins = u.Bins
+	}
+	if u.Gain != nil {
+		s.Gain = *u.Gain
+	}
+	if u.DB != nil {
+		s.DB = *u.DB
+	}
+	return s
+}
+
+// Controller reads and changes the settings for the REST API.
+type Controller interface {
+	Settings() Settings
+	// Update applies a change, returning an error if it is invalid
+	Update(u Update) error
+}
+
+// message is sent to browsers as JSON: the bins when a client connects and
+// whenever they change, then each frame.
+type message struct {
+	Type   string      `json:"type"` // "bins" or "frame"
+	Seq    uint64      `json:"seq,omitempty"`
+	Bins   []float64   `json:"bins,omitempty"`
+	Values frameValues `json:"values,omitempty"`
+}
+
+// frameValues are written with values JSON can't hold, such as -Inf dB for
+// silence, as null.
+type frameValues []float64
+
+func (v frameValues) MarshalJSON() ([]byte, error) {
+	b := []byte{'['}
+	for i, f := range v {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			b = append(b, "null"...)
+		} else {
+			b = strconv.AppendFloat(b, f, 'g', -1, 64)
+		}
+	}
+	return append(b, ']'), nil
+}
+
+const (
+	writeTimeout    = time.Second
+	shutdownTimeout = time.Second
+)
+
+// Server is a display which streams frames to every connected browser.
+// Browsers which can't keep up skip frames rather than holding up the
+// others.
+type Server struct {
+	ctrl     Controller
+	http     *http.Server
+	listener net.Listener
+	upgrader websocket.Upgrader
+
+	mu      sync.Mutex
+	bins    []byte // the encoded bins message
+	clients map[*client]bool
+	seq     uint64
+	wg      sync.WaitGroup
+}
+
+type client struct {
+	conn *websocket.Conn
+	// latest holds the newest message not yet sent, so a slow client skips
+	// to it; the bins are queued separately so they are never skipped
+	latest chan []byte
+	bins   chan []byte
+}
+
+// NewServer starts serving on addr (host:port). ctrl may be nil, in which
+// case the settings can't be read or changed.
+func NewServer(addr string, bins []float64, ctrl Controller) (*Server, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{ctrl: ctrl, listener: l, clients: make(map[*client]bool)}
+	s.SetBins(bins)
+
+	mux := http.NewServeMux()
+	files, _ := fs.Sub(static, "static")
+	mux.Handle("GET /", http.FileServerFS(files))
+	mux.HandleFunc("GET /ws", s.serveWS)
+	mux.HandleFunc("GET /api/settings", s.getSettings)
+	mux.HandleFunc("PATCH /api/settings", s.updateSettings)
+	mux.HandleFunc("PUT /api/settings", s.updateSettings)
+	s.http = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go s.http.Serve(l)
+	return s, nil
+}
+
+// Addr is the address the server is listening on.
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// SetBins tells browsers the edges of the bands, for when they change.
+func (s *Server) SetBins(bins []float64) {
+	b, _ := json.Marshal(message{Type: "bins", Bins: bins})
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bins = b
+	for c := range s.clients {
+		replace(c.bins, b)
+	}
+}
+
+// replace puts b in a channel of 1, replacing anything not yet taken.
+func replace(ch chan []byte, b []byte) {
+	for {
+		select {
+		case ch <- b:
+			return
+		default:
+		}
+		select {
+		case <-ch:
+		default:
+		}
+	}
+}
+
+// clientCount is the number of connected browsers.
+func (s *Server) clientCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.clients)
+}
+
+func (s *Server) Render(values []float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.clients) == 0 {
+		return nil
+	}
+	s.seq++
+	b, err := json.Marshal(message{Type: "frame", Seq: s.seq, Values: values})
+	if err != nil {
+		return err
+	}
+	for c := range s.clients {
+		replace(c.latest, b)
+	}
+	return nil
+}
+
+func (s *Server) serveWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // the upgrader has replied
+	}
+	c := &client{conn: conn, latest: make(chan []byte, 1), bins: make(chan []byte, 1)}
+	s.mu.Lock()
+	c.bins <- s.bins
+	s.clients[c] = true
+	s.wg.Add(1)
+	s.mu.Unlock()
+
+	// reading handles pings and notices the browser going away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, c)
+		s.mu.Unlock()
+		conn.Close()
+		s.wg.Done()
+	}()
+	for {
+		var b []byte
+		// bins go first so frames are never drawn with the wrong bins
+		select {
+		case b = <-c.bins:
+		default:
+			select {
+			case b = <-c.bins:
+			case b = <-c.latest:
+			case <-closed:
+				return
+			}
+		}
+		conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if err := conn.WriteMessage(websocket.TextMessage, b); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) getSettings(w http.ResponseWriter, r *http.Request) {
+	if s.ctrl == nil {
+		http.Error(w, "settings are not available", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, s.ctrl.Settings())
+}
+
+func (s *Server) updateSettings(w http.ResponseWriter, r *http.Request) {
+	if s.ctrl == nil {
+		http.Error(w, "settings are not available", http.StatusNotFound)
+		return
+	}
+	var u Update
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&u); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.ctrl.Update(u); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	writeJSON(w, s.ctrl.Settings())
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// Close stops the server and disconnects every browser.
+func (s *Server) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	// Shutdown doesn't wait for hijacked WebSocket connections
+	err := s.http.Shutdown(ctx)
+	s.mu.Lock()
+	for c := range s.clients {
+		c.conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+	return err
+}
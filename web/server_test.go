@@ -0,0 +1,146 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func failIfErr(t *testing.T, err error) {
+	assert.NoError(t, err)
+	if err != nil {
+		t.FailNow()
+	}
+}
+
+type fakeController struct {
+	s Settings
+}
+
+func (c *fakeController) Settings() Settings {
+	return c.s
+}
+
+func (c *fakeController) Update(u Update) error {
+	s := u.Apply(c.s)
+	if s.Gain <= 0 {
+		return errors.New("gain must be positive")
+	}
+	c.s = s
+	return nil
+}
+
+func startServer(t *testing.T, ctrl Controller) (*Server, string) {
+	s, err := NewServer("127.0.0.1:0", []float64{50, 100, 200}, ctrl)
+	failIfErr(t, err)
+	t.Cleanup(func() { s.Close() })
+	return s, s.Addr().String()
+}
+
+func dial(t *testing.T, addr string) *websocket.Conn {
+	conn, _, err := websocket.DefaultDialer.Dial("ws://"+addr+"/ws", nil)
+	failIfErr(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func read(t *testing.T, conn *websocket.Conn) message {
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	var m message
+	failIfErr(t, conn.ReadJSON(&m))
+	return m
+}
+
+func TestPage(t *testing.T) {
+	_, addr := startServer(t, nil)
+	res, err := http.Get("http://" + addr + "/")
+	failIfErr(t, err)
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Contains(t, string(body), `<script src="app.js">`)
+
+	res, err = http.Get("http://" + addr + "/app.js")
+	failIfErr(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestStream(t *testing.T) {
+	s, addr := startServer(t, nil)
+	assert.NoError(t, s.Render([]float64{1, 2}), "no clients yet")
+	conn := dial(t, addr)
+	assert.Equal(t, message{Type: "bins", Bins: []float64{50, 100, 200}}, read(t, conn))
+
+	for s.clientCount() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	failIfErr(t, s.Render([]float64{0.5, 0.25}))
+	assert.Equal(t, message{Type: "frame", Seq: 1, Values: []float64{0.5, 0.25}}, read(t, conn))
+
+	s.SetBins([]float64{1, 2})
+	assert.Equal(t, message{Type: "bins", Bins: []float64{1, 2}}, read(t, conn))
+
+	failIfErr(t, s.Close())
+	_, _, err := conn.ReadMessage()
+	assert.Error(t, err, "disconnected on close")
+}
+
+func TestStreamSilenceInDB(t *testing.T) {
+	s, addr := startServer(t, nil)
+	conn := dial(t, addr)
+	read(t, conn)
+	for s.clientCount() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	failIfErr(t, s.Render([]float64{math.Inf(-1), -12.5}))
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, b, err := conn.ReadMessage()
+	failIfErr(t, err)
+	assert.JSONEq(t, `{"type": "frame", "seq": 1, "values": [null, -12.5]}`, string(b))
+}
+
+func TestSettings(t *testing.T) {
+	ctrl := &fakeController{Settings{Bins: []float64{50, 100}, Gain: 2}}
+	_, addr := startServer(t, ctrl)
+	url := "http://" + addr + "/api/settings"
+
+	res, err := http.Get(url)
+	failIfErr(t, err)
+	var s Settings
+	failIfErr(t, json.NewDecoder(res.Body).Decode(&s))
+	res.Body.Close()
+	assert.Equal(t, ctrl.s, s)
+
+	patch := func(body string) *http.Response {
+		req, _ := http.NewRequest(http.MethodPatch, url, strings.NewReader(body))
+		res, err := http.DefaultClient.Do(req)
+		failIfErr(t, err)
+		t.Cleanup(func() { res.Body.Close() })
+		return res
+	}
+	res = patch(`{"db": true, "bins": [20, 200, 2000]}`)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	failIfErr(t, json.NewDecoder(res.Body).Decode(&s))
+	assert.Equal(t, Settings{Bins: []float64{20, 200, 2000}, Gain: 2, DB: true}, s)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, patch(`{"gain": 0}`).StatusCode)
+	assert.Equal(t, http.StatusBadRequest, patch(`{"volume": 11}`).StatusCode)
+	assert.Equal(t, 2.0, ctrl.s.Gain)
+}
+
+func TestNoSettings(t *testing.T) {
+	_, addr := startServer(t, nil)
+	res, err := http.Get("http://" + addr + "/api/settings")
+	failIfErr(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+}
@@ -0,0 +1,119 @@
+// Draws the bands streamed from /ws as bars with falling peak markers, and
+// edits the settings through /api/settings.
+"use strict";
+
+const canvas = document.getElementById("eq");
+const ctx = canvas.getContext("2d");
+const status = document.getElementById("status");
+
+const peakHold = 1000; // ms a peak stays before falling
+const peakFall = 0.5; // of full scale per second
+
+let bins = [];
+let values = [];
+let peaks = []; // {value, at}
+let db = false;
+
+function label(hz) {
+  return hz >= 1000 ? (hz / 1000).toFixed(hz >= 10000 ? 0 : 1) + "k" : Math.round(hz).toString();
+}
+
+// scale maps a band value to 0..1 of the height; dB values are shown over
+// a 60 dB range. Values which JSON can't hold, like -Inf dB, arrive as null
+// and sit at the bottom.
+function scale(v) {
+  if (v === null) {
+    return 0;
+  }
+  if (db) {
+    v = (v + 60) / 60;
+  }
+  return Math.min(Math.max(v, 0), 1);
+}
+
+function draw(now) {
+  const w = canvas.width = canvas.clientWidth * devicePixelRatio;
+  const h = canvas.height = canvas.clientHeight * devicePixelRatio;
+  const labelHeight = 20 * devicePixelRatio;
+  const barsHeight = h - labelHeight;
+  ctx.clearRect(0, 0, w, h);
+  const n = values.length;
+  const bw = w / Math.max(n, 1);
+  ctx.font = `${12 * devicePixelRatio}px sans-serif`;
+  ctx.textAlign = "center";
+  for (let i = 0; i < n; i++) {
+    const v = scale(values[i]);
+    const p = peaks[i];
+    const age = now - p.at;
+    if (age > peakHold) {
+      p.value = Math.max(p.value - peakFall * (age - peakHold) / 1000, v);
+    }
+    if (v >= p.value) {
+      p.value = v;
+      p.at = now;
+    }
+
+    const x = i * bw;
+    ctx.fillStyle = `hsl(${120 - 120 * v}, 80%, 50%)`;
+    ctx.fillRect(x + 1, barsHeight * (1 - v), bw - 2, barsHeight * v);
+    ctx.fillStyle = "#fff";
+    ctx.fillRect(x + 1, barsHeight * (1 - p.value), bw - 2, 2 * devicePixelRatio);
+    if (i + 1 < bins.length) {
+      ctx.fillStyle = "#888";
+      ctx.fillText(label((bins[i] + bins[i + 1]) / 2), x + bw / 2, h - 5 * devicePixelRatio);
+    }
+  }
+  requestAnimationFrame(draw);
+}
+
+function connect() {
+  const ws = new WebSocket(`${location.protocol === "https:" ? "wss" : "ws"}://${location.host}/ws`);
+  ws.onopen = () => status.textContent = "connected";
+  ws.onmessage = (e) => {
+    const m = JSON.parse(e.data);
+    if (m.type === "bins") {
+      bins = m.bins;
+      values = [];
+      peaks = [];
+    } else if (m.type === "frame") {
+      values = m.values;
+      while (peaks.length < values.length) {
+        peaks.push({value: 0, at: 0});
+      }
+    }
+  };
+  ws.onclose = () => {
+    status.textContent = "disconnected, retrying";
+    setTimeout(connect, 1000);
+  };
+}
+
+async function settings(change) {
+  const res = await fetch("api/settings", change ? {
+    method: "PATCH",
+    headers: {"Content-Type": "application/json"},
+    body: JSON.stringify(change),
+  } : {});
+  if (res.status === 404) {
+    return; // not available, leave the controls hidden
+  }
+  const error = document.getElementById("error");
+  if (!res.ok) {
+    error.textContent = await res.text();
+    return;
+  }
+  error.textContent = "";
+  const s = await res.json();
+  db = s.db;
+  document.getElementById("gain").value = s.gain;
+  document.getElementById("db").checked = s.db;
+  document.getElementById("controls").hidden = false;
+}
+
+document.getElementById("gain").onchange = (e) => settings({gain: Number(e.target.value)});
+document.getElementById("db").onchange = (e) => settings({db: e.target.checked});
+document.getElementById("settings").onsubmit = (e) => e.preventDefault();
+
+connect();
+settings();
+requestAnimationFrame(draw);